@@ -1,6 +1,8 @@
 package auth
 
 import (
+	"fmt"
+	"net/http"
 	"net/url"
 
 	"github.com/imroc/req/v3"
@@ -17,27 +19,69 @@ type RaycastAuth struct {
 	Email        string
 	Password     string
 	LoginResp    LoginResponse
+	// Log replaces the package logger when set, e.g. to keep a library quiet
+	Log *logrus.Entry
+	// Proxy picks the outbound proxy, http.ProxyFromEnvironment when nil
+	Proxy func(*http.Request) (*url.URL, error)
 }
 
-func (r *RaycastAuth) Login() string {
+func (r *RaycastAuth) proxy() func(*http.Request) (*url.URL, error) {
+	if r.Proxy != nil {
+		return r.Proxy
+	}
+	return http.ProxyFromEnvironment
+}
+
+func (r *RaycastAuth) logger() *logrus.Entry {
+	if r.Log != nil {
+		return r.Log
+	}
+	return Logger()
+}
+
+func (r *RaycastAuth) Login() (string, error) {
 	cli := req.C().
+		SetProxy(r.proxy()).
 		SetUserAgent("Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/16.5.2 Safari/605.1.15")
-	r1 := r.stepOne(cli)
+	r1, err := r.stepOne(cli)
+	if err != nil {
+		return "", err
+	}
 	r.stepTwo(cli, r1, r.ClientID)
-	r3 := r.stepThree(cli, r.Email, r.Password)
-	r4 := r.stepFour(cli, r3.RedirectTo)
-	r5 := r.stepFive(r4, r.ClientID, r.ClientSecret)
-	return r5.AccessToken
+	csrf, err := getCSRFToken(cli)
+	if err != nil {
+		return "", err
+	}
+	r3, err := r.stepThree(cli, csrf, r.Email, r.Password)
+	if err != nil {
+		return "", err
+	}
+	// the session cookie changes on login, so does the csrf token
+	if csrf, err = getCSRFToken(cli); err != nil {
+		return "", err
+	}
+	r4, err := r.stepFour(cli, csrf, r3.RedirectTo)
+	if err != nil {
+		return "", err
+	}
+	r5, err := r.stepFive(r4, r.ClientID, r.ClientSecret)
+	if err != nil {
+		return "", err
+	}
+	return r5.AccessToken, nil
 }
 
-func (r *RaycastAuth) stepOne(c *req.Client) StepOneResponse {
+func (r *RaycastAuth) stepOne(c *req.Client) (StepOneResponse, error) {
 	var resp StepOneResponse
 	rawResp, err := c.R().SetSuccessResult(&resp).Get("https://www.raycast.com/frontend_api/session")
 	if err != nil {
-		Logger().WithError(err).Panic("step one failed, raw response: ", rawResp)
+		return resp, fmt.Errorf("step one failed: %w", err)
+	}
+	if rawResp.IsErrorState() {
+		return resp, fmt.Errorf("step one failed: %s", rawResp.Status)
 	}
-	Logger().Info("step one success, authenticity token: ", resp.AuthenticityToken)
-	return resp
+	r.logger().Info("step one success")
+	return resp, nil
 }
 
 func (r *RaycastAuth) stepTwo(c *req.Client, prev StepOneResponse, clientID string) {
@@ -59,7 +103,7 @@ func (r *RaycastAuth) stepTwo(c *req.Client, prev StepOneResponse, clientID stri
 			"&" + "scope=")
 }
 
-func (r *RaycastAuth) stepThree(c *req.Client, email, password string) LoginResponse {
+func (r *RaycastAuth) stepThree(c *req.Client, csrf, email, password string) (LoginResponse, error) {
 	var resp LoginResponse
 	rawResp, err := c.R().SetSuccessResult(&resp).
 		SetHeaders(map[string]string{
@@ -71,7 +115,7 @@ func (r *RaycastAuth) stepThree(c *req.Client, email, password string) LoginResp
 			"Content-Type":    "application/json",
 			"Origin":          "https://www.raycast.com",
 			"Referer":         "https://www.raycast.com/users/sign_in",
-			"X-CSRF-Token":    getCSRFToken(c),
+			"X-CSRF-Token":    csrf,
 		}).
 		SetBody(map[string]map[string]string{
 			"user": {
@@ -81,16 +125,19 @@ func (r *RaycastAuth) stepThree(c *req.Client, email, password string) LoginResp
 		}).
 		Post("https://www.raycast.com/frontend_api/session")
 	if err != nil {
-		Logger().WithError(err).Panic("login failed, raw response: ", rawResp.String())
+		return resp, fmt.Errorf("login failed: %w", err)
 	}
-	Logger().Infof("login success, resp: %+v", rawResp.String())
+	if rawResp.IsErrorState() {
+		return resp, fmt.Errorf("login failed: %s", rawResp.Status)
+	}
+	r.logger().Infof("login success, user: %s", resp.User.Handle)
 	r.LoginResp = resp
-	return resp
+	return resp, nil
 }
 
-func (r *RaycastAuth) stepFour(c *req.Client, redirUrl string) string {
+func (r *RaycastAuth) stepFour(c *req.Client, csrf, redirUrl string) (string, error) {
 	url := "https://www.raycast.com" + redirUrl
-	Logger().Info("redirect url: ", url)
+	r.logger().Debug("redirect url: ", url)
 	resp, err := c.SetRedirectPolicy(req.NoRedirectPolicy()).R().SetHeaders(map[string]string{
 		"Accept":          "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8",
 		"Accept-Language": "zh-CN,zh-Hans;q=0.9",
@@ -99,21 +146,24 @@ func (r *RaycastAuth) stepFour(c *req.Client, redirUrl string) string {
 		"Sec-Fetch-Dest":  "document",
 		"Content-Type":    "application/json",
 		"Referer":         "https://www.raycast.com/users/sign_in",
-		"X-CSRF-Token":    getCSRFToken(c),
+		"X-CSRF-Token":    csrf,
 	}).Get(url)
 	if err != nil {
-		Logger().WithError(err).Panic("step four redirect failed")
+		return "", fmt.Errorf("step four redirect failed: %w", err)
 	}
 	redir := resp.GetHeader("Location")
-	return redir
+	if redir == "" {
+		return "", fmt.Errorf("step four redirect failed: no location, status %s", resp.Status)
+	}
+	return redir, nil
 }
 
-func (r *RaycastAuth) stepFive(redirUrl, clientID, clientSecret string) StepFiveResponse {
-	Logger().Info("redirect url: ", redirUrl)
+func (r *RaycastAuth) stepFive(redirUrl, clientID, clientSecret string) (StepFiveResponse, error) {
+	r.logger().Debug("redirect url: ", redirUrl)
+	var resp StepFiveResponse
 	parsedURL, err := url.Parse(redirUrl)
 	if err != nil {
-		Logger().WithError(err).Panic("parse redirect url failed")
-		return StepFiveResponse{}
+		return resp, fmt.Errorf("parse redirect url failed: %w", err)
 	}
 	qp := map[string]string{}
 	queryParams := parsedURL.Query()
@@ -123,8 +173,7 @@ func (r *RaycastAuth) stepFive(redirUrl, clientID, clientSecret string) StepFive
 		}
 	}
 
-	var resp StepFiveResponse
-	cli := req.C().SetUserAgent("Raycast/0 CFNetwork/1408.0.4 Darwin/22.5.0")
+	cli := req.C().SetProxy(r.proxy()).SetUserAgent("Raycast/0 CFNetwork/1408.0.4 Darwin/22.5.0")
 	rawResp, err := cli.R().SetSuccessResult(&resp).
 		SetHeaders(map[string]string{
 			"Content-Type":    "application/x-www-form-urlencoded",
@@ -140,21 +189,24 @@ func (r *RaycastAuth) stepFive(redirUrl, clientID, clientSecret string) StepFive
 		}).
 		Post("https://www.raycast.com/oauth/token")
 	if err != nil {
-		Logger().WithError(err).Panic("step five failed, raw response: ", rawResp.String())
+		return resp, fmt.Errorf("step five failed: %w", err)
+	}
+	if rawResp.IsErrorState() || resp.AccessToken == "" {
+		return resp, fmt.Errorf("step five failed: %s", rawResp.Status)
 	}
-	Logger().Info("step five success, resp: ", resp)
-	return resp
+	r.logger().Infof("step five success, token type: %s, scope: %s", resp.TokenType, resp.Scope)
+	return resp, nil
 }
 
-func getCSRFToken(c *req.Client) string {
+func getCSRFToken(c *req.Client) (string, error) {
 	cookies, err := c.GetCookies("https://www.raycast.com")
 	if err != nil {
-		Logger().WithError(err).Panic("get csrf token failed")
+		return "", fmt.Errorf("get csrf token failed: %w", err)
 	}
 	for _, t := range cookies {
 		if t.Name == "csrf_token" {
-			return t.Value
+			return t.Value, nil
 		}
 	}
-	return ""
+	return "", nil
 }
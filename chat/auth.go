@@ -1,44 +1,221 @@
 package chat
 
 import (
-	"raychat/auth"
-	"raychat/settings"
-)
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Ken288yzs1/raychat/auth"
+	"github.com/Ken288yzs1/raychat/metrics"
+	"github.com/Ken288yzs1/raychat/quota"
+	"github.com/Ken288yzs1/raychat/settings"
+	"github.com/Ken288yzs1/raychat/transcript"
+	"github.com/Ken288yzs1/raychat/transport"
+	"github.com/Ken288yzs1/raychat/usage"
 
-var (
-	authInstance *auth.RaycastAuth
-	token        string
-	models       map[string]string
+	"github.com/samber/lo"
 )
 
-func init() {
-	initAuth()
-	initModels()
+// Service holds the raycast session behind the chat endpoints: the token,
+// the account it belongs to and the model catalog.
+type Service struct {
+	state *upstreamState
+
+	upstreams map[string]Upstream
+
+	mu     sync.RWMutex
+	auth   *auth.RaycastAuth
+	token  string
+	models map[string]string
+	infos  map[string]ModelInfo
+	// routes maps the models of every upstream but raycast to their upstream
+	routes map[string]string
+	// provided keeps each provider's last models for when it fails a refresh
+	provided map[string][]ModelInfo
+
+	ledger *usage.Ledger
+	quotas *quota.Tracker
+	// transcripts is nil when TRANSCRIPT_DIR is unset
+	transcripts *transcript.Writer
+}
+
+// NewService returns a service recording completions in ledger, quotas and
+// transcripts, which may be nil.
+func NewService(ledger *usage.Ledger, quotas *quota.Tracker, transcripts *transcript.Writer) *Service {
+	s := &Service{
+		state:       newUpstreamState(),
+		auth:        &auth.RaycastAuth{},
+		ledger:      ledger,
+		quotas:      quotas,
+		transcripts: transcripts,
+	}
+	s.upstreams = newUpstreams(s, settings.Get().Providers)
+	return s
+}
+
+// Start logs into raycast unless a token is configured, then loads the
+// model catalog and keeps refreshing it until ctx is done.
+func (s *Service) Start(ctx context.Context) error {
+	if err := s.login(settings.Get()); err != nil {
+		return err
+	}
+	complete, err := s.refreshModels(ctx)
+	if err != nil {
+		return err
+	}
+	go s.watchModels(ctx, complete)
+	return nil
+}
+
+// Reload logs in again when a config reload changed the credentials, the
+// current session stays in use if that fails.
+func (s *Service) Reload(old, conf settings.RayConfig) {
+	if old.Token == conf.Token && old.ClientID == conf.ClientID && old.ClientSecret == conf.ClientSecret &&
+		old.Email == conf.Email && old.Password == conf.Password {
+		return
+	}
+	Logger().Info("credentials changed, logging in again")
+	if err := s.login(conf); err != nil {
+		Logger().WithError(err).Error("login with the new credentials failed, keep the current session")
+		return
+	}
+	if err := s.RefreshModels(context.Background()); err != nil {
+		Logger().WithError(err).Warn("refresh models after login failed")
+	}
+}
+
+func (s *Service) login(conf settings.RayConfig) error {
+	if conf.Token != "" || conf.UpstreamMode == UpstreamReplay {
+		// replays never reach raycast, no need to log in for them
+		s.setSession(&auth.RaycastAuth{}, conf.Token)
+		s.state.tokenReady("env")
+		return nil
+	}
+	a := &auth.RaycastAuth{
+		ClientID:     conf.ClientID,
+		ClientSecret: conf.ClientSecret,
+		Email:        conf.Email,
+		Password:     conf.Password,
+		Proxy:        transport.Proxy,
+	}
+	token, err := a.Login()
+	metrics.AuthAttempts.WithLabelValues("login", metrics.Result(err)).Inc()
+	if err != nil {
+		return fmt.Errorf("login to raycast: %w", err)
+	}
+	s.setSession(a, token)
+	s.state.tokenReady("login")
+	return nil
+}
+
+// RefreshModels reloads the catalog of every upstream. Raycast failing is
+// an error, a provider failing keeps the models it had until a refresh gets
+// through to it.
+func (s *Service) RefreshModels(ctx context.Context) error {
+	_, err := s.refreshModels(ctx)
+	return err
 }
 
-func initAuth() {
-	if settings.Get().Token != "" {
-		token = settings.Get().Token
-		authInstance = &auth.RaycastAuth{}
+// refreshModels is RefreshModels also reporting whether every provider
+// answered.
+func (s *Service) refreshModels(ctx context.Context) (bool, error) {
+	info, err := Cli(s.getToken()).GetSupportedModels(ctx)
+	if err != nil {
+		return false, err
+	}
+	s.mu.RLock()
+	previous := s.provided
+	s.mu.RUnlock()
+
+	complete := true
+	models := info.SupportedModels()
+	infos := lo.KeyBy(info.Models, func(m ModelInfo) string { return m.Model })
+	routes := map[string]string{}
+	provided := map[string][]ModelInfo{}
+	for name, u := range s.upstreams {
+		if name == RaycastUpstream {
+			continue
+		}
+		list, err := u.Models(ctx)
+		if err != nil {
+			Logger().WithError(err).Warnf("load models of %s failed, keep its last %d models", name, len(previous[name]))
+			list, complete = previous[name], false
+		}
+		provided[name] = list
+		for _, m := range list {
+			if _, ok := models[m.Model]; ok {
+				Logger().Warnf("%s also serves %s, raycast keeps it unless routed", name, m.Model)
+				continue
+			}
+			models[m.Model] = m.Provider
+			infos[m.Model] = m
+			routes[m.Model] = name
+		}
+	}
+	s.mu.Lock()
+	s.models, s.infos, s.routes, s.provided = models, infos, routes, provided
+	s.mu.Unlock()
+	s.state.modelsReady(len(models))
+	return complete, nil
+}
+
+// modelsRetryInterval is how soon a refresh that missed raycast or a
+// provider is tried again.
+const modelsRetryInterval = time.Minute
+
+// watchModels refreshes the catalog every MODELS_REFRESH_INTERVAL so that
+// new and retired models show up without a restart.
+func (s *Service) watchModels(ctx context.Context, complete bool) {
+	interval := settings.Get().ModelsRefreshInterval
+	if interval <= 0 {
 		return
 	}
-	authInstance = &auth.RaycastAuth{
-		ClientID:     settings.Get().ClientID,
-		ClientSecret: settings.Get().ClientSecret,
-		Email:        settings.Get().Email,
-		Password:     settings.Get().Password,
+	for {
+		delay := interval
+		if !complete {
+			delay = min(delay, modelsRetryInterval)
+		}
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+		var err error
+		if complete, err = s.refreshModels(ctx); err != nil {
+			Logger().WithError(err).Warn("refresh models failed, keep the current catalog")
+		}
 	}
-	token = authInstance.Login()
 }
 
-func initModels() {
-	models = Cli(getToken()).GetSupportedModels()
+func (s *Service) setSession(a *auth.RaycastAuth, token string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.auth, s.token = a, token
+}
+
+func (s *Service) getToken() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.token
 }
 
-func getToken() string {
-	return token
+// catalog is what requests are resolved against, a snapshot so that a
+// refresh can't change it halfway through a request.
+func (s *Service) catalog() Catalog {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return Catalog{Models: s.models, Info: s.infos, User: s.auth.LoginResp.User, Upstreams: s.routes}
 }
 
-func getAuthInstance() *auth.RaycastAuth {
-	return authInstance
+type Catalog struct {
+	// Models maps model names to their provider
+	Models map[string]string
+	// Info has the models' context size, scores and features
+	Info map[string]ModelInfo
+	User auth.User
+	// Upstreams maps models to the upstream serving them, raycast when missing
+	Upstreams map[string]string
 }
@@ -0,0 +1,140 @@
+package chat
+
+import (
+	"cmp"
+	"encoding/json"
+	"net/http"
+	"slices"
+
+	"github.com/Ken288yzs1/raychat/auth"
+	"github.com/Ken288yzs1/raychat/keys"
+	"github.com/Ken288yzs1/raychat/quota"
+
+	"github.com/gin-gonic/gin"
+	"github.com/samber/lo"
+)
+
+// virtual models picking a real one from the catalog for each request
+const (
+	AutoModel      = "auto"
+	AutoFastModel  = "auto-fast"
+	AutoSmartModel = "auto-smart"
+)
+
+// autoRanks orders the candidates of each auto model, the first score
+// decides and the next ones break ties.
+var autoRanks = map[string]func(m ModelInfo) []float64{
+	AutoModel: func(m ModelInfo) []float64 {
+		return []float64{m.Intelligence + float64(m.Speed), m.Intelligence}
+	},
+	AutoFastModel: func(m ModelInfo) []float64 {
+		return []float64{float64(m.Speed), m.Intelligence}
+	},
+	AutoSmartModel: func(m ModelInfo) []float64 {
+		return []float64{m.Intelligence, float64(m.Speed)}
+	},
+}
+
+// IsAutoModel is whether model is one of the virtual auto models.
+func IsAutoModel(model string) bool {
+	_, ok := autoRanks[model]
+	return ok
+}
+
+// autoNeeds is what a request requires of the model answering it.
+type autoNeeds struct {
+	// tokens is the estimated prompt length
+	tokens    int
+	images    bool
+	reasoning bool
+}
+
+func (r OpenAIRequest) autoNeeds(cat Catalog) autoNeeds {
+	needs := autoNeeds{
+		tokens:    r.ToRayChatRequest(cat).PromptTokens(),
+		reasoning: r.ReasoningEffort != "",
+	}
+	for _, m := range r.Messages {
+		raw, _ := json.Marshal(m)
+		var parted OpenAIPartedMessage
+		if json.Unmarshal(raw, &parted) != nil {
+			continue
+		}
+		if lo.ContainsBy(parted.Content, func(p ChatMessagePart) bool { return p.Type == "image_url" }) {
+			needs.images = true
+		}
+	}
+	return needs
+}
+
+// fits is whether m can answer a request with those needs. Unknown context
+// sizes are taken to fit, the prompt may use three quarters of a known one
+// so that there is room for the answer.
+func (n autoNeeds) fits(m ModelInfo) bool {
+	if m.Context > 0 && n.tokens > m.Context*1000*3/4 {
+		return false
+	}
+	if n.images && !lo.Contains(m.Features, "vision") {
+		return false
+	}
+	return !n.reasoning || lo.Contains(m.Features, "reasoning")
+}
+
+// subscribed is whether the account may use m. Without a login, e.g. with
+// TOKEN, the account is unknown and the catalog is trusted as it is.
+func (c Catalog) subscribed(m ModelInfo) bool {
+	if !m.RequiresBetterAi || c.User.Email == "" {
+		return true
+	}
+	if len(c.User.AiChatModels) > 0 {
+		return lo.ContainsBy(c.User.AiChatModels, func(a auth.AiChatModels) bool { return a.Model == m.Model })
+	}
+	return c.User.HasActiveSubscription
+}
+
+// autoCandidates lists the models auto may pick for needs, best first.
+func (c Catalog) autoCandidates(auto string, needs autoNeeds, key *keys.Key, quotas *quota.Tracker) []ModelInfo {
+	rank := autoRanks[auto]
+	candidates := lo.Filter(lo.Values(c.Info), func(m ModelInfo, _ int) bool {
+		if !needs.fits(m) || !c.subscribed(m) {
+			return false
+		}
+		return key == nil || key.AllowsModel(m.Model) && quotas.Check(*key, m.Model) == nil
+	})
+	slices.SortFunc(candidates, func(a, b ModelInfo) int {
+		if d := slices.Compare(rank(b), rank(a)); d != 0 {
+			return d
+		}
+		return cmp.Compare(a.Model, b.Model)
+	})
+	return candidates
+}
+
+// selectAutoModel replaces an auto model with the best model for the
+// request and names the auto model in the X-Model-Auto header. It answers
+// the request itself and returns false when no model qualifies.
+func (s *Service) selectAutoModel(c *gin.Context, cat Catalog, req *OpenAIRequest) bool {
+	auto := req.Model
+	if !IsAutoModel(auto) {
+		return true
+	}
+	var key *keys.Key
+	if k, ok := keys.FromContext(c); ok {
+		key = &k
+	}
+	needs := req.autoNeeds(cat)
+	candidates := cat.autoCandidates(auto, needs, key, s.quotas)
+	if len(candidates) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": gin.H{
+			"message": "no model available to " + auto + " fits this request",
+			"type":    "invalid_request_error",
+			"code":    "no_model_available",
+		}})
+		return false
+	}
+	req.Model = candidates[0].Model
+	requestLogger(c).Infof("%s picked %s out of %d models, prompt tokens: %d, images: %v, reasoning: %v",
+		auto, req.Model, len(candidates), needs.tokens, needs.images, needs.reasoning)
+	c.Header("X-Model-Auto", auto)
+	return true
+}
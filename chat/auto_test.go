@@ -0,0 +1,85 @@
+package chat
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/Ken288yzs1/raychat/auth"
+	"github.com/Ken288yzs1/raychat/keys"
+	"github.com/Ken288yzs1/raychat/quota"
+	"github.com/Ken288yzs1/raychat/usage"
+
+	"github.com/samber/lo"
+)
+
+// autoCatalog is a fake catalog: fast, smart and the two mids are free,
+// premium needs advanced ai.
+func autoCatalog(user auth.User) Catalog {
+	infos := []ModelInfo{
+		{Model: "fast", Speed: 5, Intelligence: 2, Context: 16},
+		{Model: "smart", Speed: 2, Intelligence: 5, Context: 200, Features: []string{"reasoning", "vision"}},
+		{Model: "mid", Speed: 4, Intelligence: 4, Context: 128, Features: []string{"vision"}},
+		// same scores as mid, the name breaks the tie
+		{Model: "also-mid", Speed: 4, Intelligence: 4},
+		{Model: "premium", Speed: 3, Intelligence: 4.9, RequiresBetterAi: true},
+	}
+	return Catalog{
+		Models: lo.SliceToMap(infos, func(m ModelInfo) (string, string) { return m.Model, "test" }),
+		Info:   lo.KeyBy(infos, func(m ModelInfo) string { return m.Model }),
+		User:   user,
+	}
+}
+
+func TestAutoCandidates(t *testing.T) {
+	subscribed := auth.User{Email: "a@b.c", HasActiveSubscription: true}
+	free := auth.User{Email: "a@b.c"}
+	tests := []struct {
+		name  string
+		auto  string
+		user  auth.User
+		needs autoNeeds
+		key   *keys.Key
+		// spent are models the key used once, a request each
+		spent []string
+		want  []string
+	}{
+		{name: "auto", auto: AutoModel, user: subscribed, want: []string{"also-mid", "mid", "premium", "smart", "fast"}},
+		{name: "auto-fast", auto: AutoFastModel, user: subscribed, want: []string{"fast", "also-mid", "mid", "premium", "smart"}},
+		{name: "auto-smart", auto: AutoSmartModel, user: subscribed, want: []string{"smart", "premium", "also-mid", "mid", "fast"}},
+		{name: "without subscription", auto: AutoModel, user: free, want: []string{"also-mid", "mid", "smart", "fast"}},
+		// without a login the account is unknown, the catalog is trusted
+		{name: "token only", auto: AutoModel, want: []string{"also-mid", "mid", "premium", "smart", "fast"}},
+		{name: "reasoning", auto: AutoFastModel, user: subscribed, needs: autoNeeds{reasoning: true}, want: []string{"smart"}},
+		{name: "images", auto: AutoModel, user: subscribed, needs: autoNeeds{images: true}, want: []string{"mid", "smart"}},
+		{name: "long prompt", auto: AutoFastModel, user: subscribed, needs: autoNeeds{tokens: 20000}, want: []string{"also-mid", "mid", "premium", "smart"}},
+		{
+			name: "key allowlist", auto: AutoModel, user: subscribed,
+			key:  &keys.Key{ID: "key_1", AllowedModels: []string{"fast", "smart", "gone"}},
+			want: []string{"smart", "fast"},
+		},
+		{
+			name: "key quota spent", auto: AutoModel, user: subscribed,
+			key:   &keys.Key{ID: "key_1", Quotas: []keys.Quota{{Period: "day", MaxRequests: 1, Models: []string{"mid", "also-mid"}}}},
+			spent: []string{"mid"},
+			want:  []string{"premium", "smart", "fast"},
+		},
+		{name: "nothing fits", auto: AutoModel, user: free, needs: autoNeeds{reasoning: true, tokens: 200000}, want: []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ledger, err := usage.Open("", 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			quotas := quota.New(ledger)
+			for _, model := range tt.spent {
+				quotas.Record(*tt.key, usage.Record{Time: time.Now(), KeyID: tt.key.ID, Model: model})
+			}
+			got := lo.Map(autoCatalog(tt.user).autoCandidates(tt.auto, tt.needs, tt.key, quotas), func(m ModelInfo, _ int) string { return m.Model })
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("candidates = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
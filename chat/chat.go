@@ -1,17 +1,30 @@
 package chat
 
 import (
-	"bytes"
-	"encoding/json"
+	"context"
 	"net/http"
-)
 
-const (
-	url = "https://backend.raycast.com/api/v1/ai/chat_completions"
+	"github.com/Ken288yzs1/raychat/raycast"
+	"github.com/Ken288yzs1/raychat/tracing"
+	"github.com/Ken288yzs1/raychat/transport"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type RayChat struct {
 	Token string
+	// HTTPClient replaces the shared upstream client when set
+	HTTPClient *http.Client
+}
+
+func (r *RayChat) httpClient() *http.Client {
+	if r.HTTPClient != nil {
+		return r.HTTPClient
+	}
+	return transport.Client()
 }
 
 func Cli(token string) *RayChat {
@@ -20,28 +33,31 @@ func Cli(token string) *RayChat {
 	}
 }
 
-func (r *RayChat) Chat(request RayChatRequest) (*http.Response, error) {
-	rawReq, err := json.Marshal(request)
+func (r *RayChat) Chat(ctx context.Context, request RayChatRequest) (res *http.Response, err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "raycast.chat",
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("raycast.model", request.Model),
+			attribute.String("raycast.provider", request.Provider),
+			attribute.Int("raycast.messages", len(request.Messages)),
+		),
+	)
+	defer func() { tracing.End(span, err) }()
+
+	req, err := raycast.NewHTTPChatRequest(ctx, r.Token, raycast.ChatRequest(request))
 	if err != nil {
 		return nil, err
 	}
 
-	client := &http.Client{}
-	payload := bytes.NewReader(rawReq)
-	req, err := http.NewRequest(http.MethodPost, url, payload)
+	res, err = r.do(req, requestHash(request), request)
 	if err != nil {
-		return nil, err
-	}
-	req.Header.Add("Accept", "application/json")
-	req.Header.Add("Accept-Language", "zh-CN,zh-Hans;q=0.9")
-	req.Header.Add("User-Agent", "Raycast/0 CFNetwork/1408.0.4 Darwin/22.5.0")
-	req.Header.Add("Content-Type", "application/json")
-	req.Header.Add("Authorization", "Bearer "+r.Token)
-
-	res, err := client.Do(req)
-	if err != nil || res.StatusCode != http.StatusOK {
 		return res, err
 	}
+	span.SetAttributes(semconv.HTTPResponseStatusCode(res.StatusCode))
+	if res.StatusCode != http.StatusOK {
+		span.SetStatus(codes.Error, res.Status)
+		return res, nil
+	}
 
 	return res, nil
-}
\ No newline at end of file
+}
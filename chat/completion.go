@@ -0,0 +1,74 @@
+package chat
+
+import (
+	"context"
+	"time"
+
+	"github.com/Ken288yzs1/raychat/metrics"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+const systemFingerprint = "fp_raychat"
+
+// CompletionMeta holds what every chunk of one completion has in common, so
+// clients grouping deltas by id see a single completion.
+type CompletionMeta struct {
+	ID           string
+	Created      int
+	Model        string
+	PromptTokens int
+	// ModelLabel is Model as a metrics label
+	ModelLabel string
+
+	upstreamStart  time.Time
+	gotFirstToken  bool
+	firstTokenSpan trace.Span
+}
+
+// NewCompletionMeta picks a fresh completion id. It never comes from the
+// client's X-Request-ID, which would let clients collide ids, logs carry
+// both instead.
+func NewCompletionMeta(model string) *CompletionMeta {
+	return &CompletionMeta{
+		ID:      "chatcmpl-" + generateRandomString(29),
+		Created: int(time.Now().Unix()),
+		Model:   model,
+		// replaced by a real span once the upstream request starts
+		firstTokenSpan: trace.SpanFromContext(context.Background()),
+	}
+}
+
+// observeFirstToken records the time to first token once the first text or
+// reasoning arrives.
+func (m *CompletionMeta) observeFirstToken(r RayChatStreamResponse) {
+	if m.gotFirstToken || (r.Text == "" && r.Reasoning == "") {
+		return
+	}
+	m.gotFirstToken = true
+	m.firstTokenSpan.End()
+	metrics.UpstreamTTFT.WithLabelValues(m.ModelLabel).Observe(time.Since(m.upstreamStart).Seconds())
+}
+
+// RoleChunk is the opening chunk of a stream, the only one carrying the role.
+func (m *CompletionMeta) RoleChunk() OpenAIStreamResponse {
+	resp := m.chunk(nil)
+	resp.Choices[0].Delta = Delta{Role: "assistant"}
+	return resp
+}
+
+func (m *CompletionMeta) chunk(finishReason *string) OpenAIStreamResponse {
+	return OpenAIStreamResponse{
+		ID:                m.ID,
+		Object:            "chat.completion.chunk",
+		Created:           m.Created,
+		Model:             m.Model,
+		SystemFingerprint: systemFingerprint,
+		Choices: []StreamChoices{
+			{
+				Index:        0,
+				FinishReason: finishReason,
+			},
+		},
+	}
+}
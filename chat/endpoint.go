@@ -3,64 +3,181 @@ package chat
 import (
 	"bufio"
 	"bytes"
+	"context"
 	"io"
 	"net/http"
+	"time"
+
+	"github.com/Ken288yzs1/raychat/keys"
+	"github.com/Ken288yzs1/raychat/logging"
+	"github.com/Ken288yzs1/raychat/metrics"
+	"github.com/Ken288yzs1/raychat/settings"
+	"github.com/Ken288yzs1/raychat/tracing"
+	"github.com/Ken288yzs1/raychat/transcript"
+	"github.com/Ken288yzs1/raychat/usage"
 
 	"github.com/gin-gonic/gin"
 	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 )
 
-func ChatEndpoint(c *gin.Context) {
+func (s *Service) ChatEndpoint(c *gin.Context) {
+	start := time.Now()
 	strOriginReq := &OpenAIRequest{}
 	ByteBody, _ := io.ReadAll(c.Request.Body)
 	c.Request.Body = io.NopCloser(bytes.NewBuffer(ByteBody))
 	if err := c.ShouldBindJSON(strOriginReq); err != nil {
 		// c.Request.Body = io.NopCloser(bytes.NewBuffer(ByteBody))
-		logrus.WithError(err).Errorf("bind json error, request: %+v", string(ByteBody))
+		requestLogger(c).WithError(err).Errorf("bind json error, body size: %d", len(ByteBody))
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	r, err := Cli(getToken()).Chat(strOriginReq.ToRayChatRequest(getAuthInstance()))
-	if err != nil || r.StatusCode != http.StatusOK {
+	cat := s.catalog()
+	if !s.selectAutoModel(c, cat, strOriginReq) {
+		return
+	}
+	model, _ := strOriginReq.GetRequestModel(cat)
+	if key, ok := keys.FromContext(c); ok {
+		if model, ok = s.applyQuota(c, cat, key, strOriginReq); !ok {
+			return
+		}
+		if !key.AllowsModel(model) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "model " + model + " is not allowed for this key", "code": "model_not_allowed"})
+			return
+		}
+	}
+	meta := NewCompletionMeta(model)
+	meta.ModelLabel = cat.ModelLabel(model)
+	metrics.SetModel(c, meta.ModelLabel)
+	renderer := newReasoningRenderer(requestReasoningFormat(c))
+	requestLogger(c).WithField("completion_id", meta.ID).Infof("chat completion, model: %s, stream: %v", model, strOriginReq.Stream)
+
+	_, translateSpan := tracing.Tracer().Start(c.Request.Context(), "translate")
+	rayReq := strOriginReq.ToRayChatRequest(cat)
+	meta.PromptTokens = rayReq.PromptTokens()
+	translateSpan.SetAttributes(
+		attribute.Int("openai.messages", len(strOriginReq.Messages)),
+		attribute.Int("raycast.messages", len(rayReq.Messages)),
+		attribute.Int("raycast.prompt_tokens", meta.PromptTokens),
+	)
+	translateSpan.End()
+
+	outcome := usage.OutcomeError
+	var received RayChatStreamResponses
+	defer func() {
+		s.recordUsage(c, meta, rayReq.Provider, strOriginReq.Stream, start, outcome)
+		s.recordTranscript(c, meta, strOriginReq, rayReq, received, start, outcome)
+	}()
+
+	ctx := c.Request.Context()
+	meta.upstreamStart = time.Now()
+	_, meta.firstTokenSpan = tracing.Tracer().Start(ctx, "raycast.first_token")
+	defer meta.firstTokenSpan.End()
+	sent := s.send(c, cat, model, rayReq, strOriginReq.Stream)
+	up, r, err := sent.upstream, sent.res, sent.err
+	if sent.model != model {
+		// report what actually answered
+		c.Header("X-Model-Fallback-From", model)
+		model, meta.Model, rayReq = sent.model, sent.model, sent.request
+		meta.ModelLabel = cat.ModelLabel(model)
+		metrics.SetModel(c, meta.ModelLabel)
+	}
+	c.Header("X-Model-Used", model)
+	if err != nil {
+		if ctx.Err() != nil {
+			requestLogger(c).WithError(ctx.Err()).Warnf("client disconnected before %s responded", up.Name())
+			outcome = usage.OutcomeCancelled
+			return
+		}
+		requestLogger(c).WithError(err).Errorf("request to %s error", up.Name())
+		s.upstreamFailed(up, 0, err.Error())
+		c.JSON(http.StatusBadRequest, gin.H{"error": "request to " + up.Name() + " error", "code": 400})
+		return
+	}
+	if r.StatusCode != http.StatusOK {
+		defer r.Body.Close()
 		data, err := io.ReadAll(r.Body)
-		logrus.WithError(err).Errorf("request to raycast error, request: %+v, body: %+v", r, string(data))
-		c.JSON(http.StatusBadRequest, gin.H{"error": "request to raycast error", "code": 400})
+		requestLogger(c).WithError(err).Errorf("request to %s error, status: %s, body: %s", up.Name(), r.Status, string(data))
+		s.upstreamFailed(up, r.StatusCode, r.Status)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "request to " + up.Name() + " error", "code": 400})
 		return
 	}
 
+	if up.Name() == RaycastUpstream {
+		s.state.upstreamSucceeded()
+	}
+
+	_, responseSpan := tracing.Tracer().Start(ctx, "raycast.response", trace.WithAttributes(attribute.Bool("stream", strOriginReq.Stream)))
+	defer func() {
+		tokens, _ := usage.FromContext(c)
+		responseSpan.SetAttributes(
+			attribute.String("outcome", outcome),
+			attribute.Int("tokens.completion", tokens.Completion),
+			attribute.Int("tokens.reasoning", tokens.Reasoning),
+		)
+		if outcome != usage.OutcomeOK {
+			responseSpan.SetStatus(codes.Error, outcome)
+		}
+		responseSpan.End()
+	}()
+
 	switch strOriginReq.Stream {
 	case true:
-		streamResp(c, strOriginReq, r)
+		received, outcome = streamResp(c, meta, renderer, r, sent.waited)
 	default:
-		plainResp(c, strOriginReq, r)
+		received, outcome = plainResp(c, meta, renderer, r)
 	}
 }
 
-func plainResp(c *gin.Context, req *OpenAIRequest, resp *http.Response) {
+func plainResp(c *gin.Context, meta *CompletionMeta, renderer *reasoningRenderer, resp *http.Response) (RayChatStreamResponses, string) {
 	defer resp.Body.Close()
 
-	model, _ := req.GetRequestModel(getAuthInstance())
-
+	ctx := c.Request.Context()
 	rayChatResps := *new(RayChatStreamResponses)
 	scanner := bufio.NewScanner(resp.Body)
 	for scanner.Scan() {
-		event := scanner.Text()
-		if len(event) == 0 {
+		if ctx.Err() != nil {
+			break
+		}
+		rayChatResp, ok, err := ParseStreamEvent(scanner.Text())
+		if err != nil {
+			requestLogger(c).WithError(err).Error("bad event from upstream")
+			usage.Set(c, rayChatResps.Tokens(meta.PromptTokens))
+			c.JSON(http.StatusBadGateway, gin.H{"error": err.Error(), "code": "upstream_error"})
+			return rayChatResps, usage.OutcomeError
+		}
+		if !ok {
 			continue
 		}
-		rayChatResp := RayChatStreamResponse{}.FromEventString(event)
+		meta.observeFirstToken(rayChatResp)
 		rayChatResps = append(rayChatResps, rayChatResp)
 	}
+	tokens := rayChatResps.Tokens(meta.PromptTokens)
+	usage.Set(c, tokens)
+	if ctx.Err() != nil {
+		logCancelled(ctx, requestLogger(c).WithField("completion_id", meta.ID), rayChatResps, nil)
+		return rayChatResps, usage.OutcomeCancelled
+	}
 	if scanner.Err() != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": scanner.Err().Error()})
-		return
+		return rayChatResps, usage.OutcomeError
+	}
+	rendered := make(RayChatStreamResponses, 0, len(rayChatResps)+1)
+	for _, rayChatResp := range rayChatResps {
+		rendered = append(rendered, renderer.Render(rayChatResp))
 	}
-	openaiResp := rayChatResps.ToOpenAIResponse(model)
+	rendered = append(rendered, RayChatStreamResponse{Text: renderer.Close()})
+	openaiResp := rendered.ToOpenAIResponse(meta, tokens)
 	c.JSON(http.StatusOK, openaiResp)
+	return rayChatResps, usage.OutcomeOK
 }
 
-func streamResp(c *gin.Context, req *OpenAIRequest, resp *http.Response) {
+// streamResp relays resp as it comes, waited is how long the fallback
+// already waited for raycast's first event and counts towards its timeout.
+func streamResp(c *gin.Context, meta *CompletionMeta, renderer *reasoningRenderer, resp *http.Response, waited time.Duration) (RayChatStreamResponses, string) {
 	c.Writer.Header().Set("Content-Type", "text/event-stream")
 	c.Writer.Header().Set("Cache-Control", "no-cache")
 	c.Writer.Header().Set("Connection", "keep-alive")
@@ -76,27 +193,170 @@ func streamResp(c *gin.Context, req *OpenAIRequest, resp *http.Response) {
 		resp.Body.Close()
 	}()
 
-	model, _ := req.GetRequestModel(getAuthInstance())
+	metrics.ActiveStreams.Inc()
+	defer metrics.ActiveStreams.Dec()
 
-	scanner := bufio.NewScanner(resp.Body)
-	for scanner.Scan() {
-		event := scanner.Text()
-		if len(event) == 0 {
-			c.Writer.WriteString("\n")
+	c.Writer.WriteString(meta.RoleChunk().ToEventString() + "\n\n")
+	c.Writer.Flush()
+
+	ctx := c.Request.Context()
+	log := requestLogger(c).WithField("completion_id", meta.ID)
+	conf := settings.Get()
+	streamed := *new(RayChatStreamResponses)
+	defer func() {
+		usage.Set(c, streamed.Tokens(meta.PromptTokens))
+	}()
+
+	reader := newEventReader(resp.Body)
+	defer reader.Close()
+	firstByte := conf.StreamFirstByteTimeout
+	if firstByte > 0 {
+		firstByte = max(firstByte-waited, time.Millisecond)
+	}
+	timeout := newIdleTimer(firstByte, conf.StreamIdleTimeout)
+	defer timeout.Stop()
+	var heartbeat <-chan time.Time
+	if conf.StreamHeartbeat > 0 {
+		ticker := time.NewTicker(conf.StreamHeartbeat)
+		defer ticker.Stop()
+		heartbeat = ticker.C
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			logCancelled(ctx, log, streamed, nil)
+			return streamed, usage.OutcomeCancelled
+		case <-heartbeat:
+			// SSE comment, ignored by clients but keeps proxies from closing the connection
+			if _, err := c.Writer.WriteString(": ping\n\n"); err != nil {
+				logCancelled(ctx, log, streamed, err)
+				return streamed, usage.OutcomeCancelled
+			}
+			c.Writer.Flush()
+		case <-timeout.C():
+			log.Warnf("raycast %s timeout, aborting stream", timeout.Phase())
+			c.Writer.WriteString(streamErrorEvent("raycast "+timeout.Phase()+" timeout", "upstream_timeout") + "\n\n")
+			return streamed, usage.OutcomeTimeout
+		case event, ok := <-reader.Events():
+			if !ok {
+				if rest := renderer.Close(); rest != "" {
+					c.Writer.WriteString(RayChatStreamResponse{Text: rest}.ToOpenAISteamResponse(meta).ToEventString() + "\n\n")
+				}
+				if reader.Err() != nil {
+					log.WithError(reader.Err()).Error("read raycast stream error")
+					return streamed, usage.OutcomeError
+				}
+				return streamed, usage.OutcomeOK
+			}
+			timeout.Reset()
+			if len(event) == 0 {
+				// every event written below ends itself, relaying upstream
+				// separators would leave stray blank lines for dropped chunks
+				continue
+			}
+			rayChatResp, ok, err := ParseStreamEvent(event)
+			if err != nil {
+				log.WithError(err).Error("bad event from upstream, aborting stream")
+				c.Writer.WriteString(streamErrorEvent(err.Error(), "upstream_error") + "\n\n")
+				return streamed, usage.OutcomeError
+			}
+			if !ok {
+				continue
+			}
+			meta.observeFirstToken(rayChatResp)
+			streamed = append(streamed, rayChatResp)
+			rayChatResp = renderer.Render(rayChatResp)
+			if rayChatResp.Text == "" && rayChatResp.Reasoning == "" && rayChatResp.FinishReason == nil {
+				// nothing left once reasoning is hidden
+				continue
+			}
+			openAIResp := rayChatResp.ToOpenAISteamResponse(meta)
+			eventResp := openAIResp.ToEventString()
+			_, err = c.Writer.WriteString(eventResp + "\n\n")
+			if err != nil {
+				c.Writer.WriteString("data: {\"finish_reason\":\"stop\"}" + "\n")
+				logCancelled(ctx, log, streamed, err)
+				return streamed, usage.OutcomeCancelled
+			}
 			c.Writer.Flush()
-			continue
-		}
-		rayChatResp := RayChatStreamResponse{}.FromEventString(event)
-		openAIResp := rayChatResp.ToOpenAISteamResponse(model)
-		eventResp := openAIResp.ToEventString()
-		_, err := c.Writer.WriteString(eventResp + "\n")
-		if err != nil {
-			c.Writer.WriteString("data: {\"finish_reason\":\"stop\"}" + "\n")
-			return
 		}
-		c.Writer.Flush()
 	}
-	if scanner.Err() != nil {
+}
+
+// upstreamFailed only counts against raycast's health, the status page is
+// about the raycast account.
+func (s *Service) upstreamFailed(up Upstream, status int, reason string) {
+	if up.Name() == RaycastUpstream {
+		s.state.upstreamFailed(status, reason)
+	}
+}
+
+// logCancelled reports a request whose client went away before raycast
+// finished; the upstream body is closed by the request context, so whatever
+// raycast had produced up to this point is thrown away. writeErr is the
+// failed write that noticed it, it is logged while ctx is still alive.
+func logCancelled(ctx context.Context, log *logrus.Entry, resps RayChatStreamResponses, writeErr error) {
+	text, reasoning := 0, 0
+	for _, r := range resps {
+		text += len(r.Text)
+		reasoning += len(r.Reasoning)
+	}
+	err := ctx.Err()
+	if err == nil {
+		err = writeErr
+	}
+	log.WithError(err).WithFields(logrus.Fields{
+		"chunks":          len(resps),
+		"text_bytes":      text,
+		"reasoning_bytes": reasoning,
+	}).Warn("client disconnected, upstream request aborted and output discarded")
+}
+
+func (s *Service) recordUsage(c *gin.Context, meta *CompletionMeta, provider string, stream bool, start time.Time, outcome string) {
+	tokens, ok := usage.FromContext(c)
+	if !ok {
+		tokens = usage.Tokens{Prompt: meta.PromptTokens}
+	}
+	metrics.Tokens.WithLabelValues(meta.ModelLabel, "prompt").Add(float64(tokens.Prompt))
+	metrics.Tokens.WithLabelValues(meta.ModelLabel, "completion").Add(float64(tokens.Completion))
+	metrics.Tokens.WithLabelValues(meta.ModelLabel, "reasoning").Add(float64(tokens.Reasoning))
+	record := usage.Record{
+		Time:      start,
+		KeyID:     usage.KeyID(c),
+		Model:     meta.Model,
+		Provider:  provider,
+		Stream:    stream,
+		Tokens:    tokens,
+		LatencyMs: time.Since(start).Milliseconds(),
+		Outcome:   outcome,
+	}
+	if key, ok := keys.FromContext(c); ok {
+		s.quotas.Record(key, record)
+		return
+	}
+	s.ledger.Add(record)
+}
+
+func (s *Service) recordTranscript(c *gin.Context, meta *CompletionMeta, req *OpenAIRequest, rayReq RayChatRequest, resps RayChatStreamResponses, start time.Time, outcome string) {
+	w := s.transcripts
+	if w == nil {
+		return
+	}
+	if key, ok := keys.FromContext(c); ok && key.NoTranscripts {
 		return
 	}
+	content, reasoning := resps.Join()
+	w.Write(transcript.Record{
+		Time:           start,
+		RequestID:      logging.GetRequestID(c),
+		CompletionID:   meta.ID,
+		KeyID:          usage.KeyID(c),
+		Model:          meta.Model,
+		Outcome:        outcome,
+		Request:        req,
+		RayChatRequest: rayReq,
+		Content:        content,
+		Reasoning:      reasoning,
+	})
 }
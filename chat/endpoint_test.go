@@ -0,0 +1,44 @@
+package chat
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+)
+
+func TestLogCancelled(t *testing.T) {
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	writeErr := errors.New("write: broken pipe")
+	tests := []struct {
+		name     string
+		ctx      context.Context
+		writeErr error
+		want     error
+	}{
+		{name: "context cancelled", ctx: cancelled, want: context.Canceled},
+		{name: "write failed first", ctx: context.Background(), writeErr: writeErr, want: writeErr},
+		{name: "context wins", ctx: cancelled, writeErr: writeErr, want: context.Canceled},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logger, hook := test.NewNullLogger()
+			log := logger.WithFields(logrus.Fields{"prefix": "chat", "request_id": "req-1"})
+			logCancelled(tt.ctx, log, RayChatStreamResponses{{Text: "hi"}, {Reasoning: "hmm"}}, tt.writeErr)
+
+			entry := hook.LastEntry()
+			if entry == nil {
+				t.Fatal("nothing logged")
+			}
+			if err, _ := entry.Data[logrus.ErrorKey].(error); !errors.Is(err, tt.want) {
+				t.Errorf("error = %v, want %v", entry.Data[logrus.ErrorKey], tt.want)
+			}
+			if entry.Data["request_id"] != "req-1" || entry.Data["chunks"] != 2 || entry.Data["text_bytes"] != 2 {
+				t.Errorf("fields = %v", entry.Data)
+			}
+		})
+	}
+}
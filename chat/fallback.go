@@ -0,0 +1,211 @@
+package chat
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Ken288yzs1/raychat/keys"
+	"github.com/Ken288yzs1/raychat/metrics"
+	"github.com/Ken288yzs1/raychat/settings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// attempt is one model of a fallback chain and how its upstream answered.
+type attempt struct {
+	model    string
+	upstream Upstream
+	request  RayChatRequest
+	res      *http.Response
+	err      error
+	// waited is how long send already waited for the first event
+	waited time.Duration
+}
+
+var errFirstByteTimeout = errors.New("first byte timeout")
+
+// fallbackChain lists the models to try for model, skipping fallbacks the
+// key may not use or has no quota left for and those nobody serves.
+func (s *Service) fallbackChain(c *gin.Context, cat Catalog, model string) []string {
+	chain := []string{model}
+	key, hasKey := keys.FromContext(c)
+	for _, next := range settings.Get().FallbackChain(model) {
+		if _, ok := cat.Models[next]; !ok && cat.Upstream(next) == RaycastUpstream {
+			continue
+		}
+		if hasKey && (!key.AllowsModel(next) || s.quotas.Check(key, next) != nil) {
+			continue
+		}
+		chain = append(chain, next)
+	}
+	return chain
+}
+
+// retryable is whether a failed attempt is worth repeating on another model.
+func retryable(res *http.Response, err error) bool {
+	if err != nil {
+		return true
+	}
+	return res.StatusCode == http.StatusRequestTimeout || res.StatusCode == http.StatusTooManyRequests || res.StatusCode >= 500
+}
+
+// peekWait is how long send waits for the first event of an attempt that
+// has fallbacks left. Streams wait no longer than a heartbeat, so that the
+// client hears from us in time, and no longer than the first byte timeout,
+// which fails the attempt when it runs out. Zero waits for good.
+func peekWait(stream bool) (wait time.Duration, timesOut bool) {
+	if !stream {
+		return 0, false
+	}
+	conf := settings.Get()
+	heartbeat, firstByte := conf.StreamHeartbeat, conf.StreamFirstByteTimeout
+	if firstByte > 0 && (heartbeat <= 0 || firstByte <= heartbeat) {
+		return firstByte, true
+	}
+	return heartbeat, false
+}
+
+// peekError waits up to wait for the first event of a 200 response, raycast
+// reports an overloaded model that way. ok is false when wait ran out
+// first. The body is left as it was, a goroutine keeps passing it on.
+func peekError(res *http.Response, wait time.Duration) (ok bool, err error) {
+	body := res.Body
+	pr, pw := io.Pipe()
+	first := make(chan error, 1)
+	go func() {
+		br := bufio.NewReader(body)
+		// nothing reads the pipe before the peek is over, keep what comes
+		// until then
+		var peeked bytes.Buffer
+		peeking := true
+		for {
+			line, readErr := br.ReadString('\n')
+			if peeking {
+				peeked.WriteString(line)
+				if _, ok, parseErr := ParseStreamEvent(strings.TrimSpace(line)); ok || parseErr != nil || readErr != nil {
+					first <- parseErr
+					peeking = false
+					line = peeked.String()
+				}
+			}
+			if !peeking && line != "" {
+				if _, err := io.WriteString(pw, line); err != nil {
+					// the body was closed
+					return
+				}
+			}
+			if readErr != nil {
+				if readErr == io.EOF {
+					readErr = nil
+				}
+				pw.CloseWithError(readErr)
+				return
+			}
+		}
+	}()
+	res.Body = peekedBody{PipeReader: pr, body: body}
+
+	var timeout <-chan time.Time
+	if wait > 0 {
+		timer := time.NewTimer(wait)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+	select {
+	case err := <-first:
+		return true, err
+	case <-timeout:
+		return false, nil
+	}
+}
+
+// peekedBody is what peekError passes on, closing it also stops the
+// goroutine reading the upstream body.
+type peekedBody struct {
+	*io.PipeReader
+	body io.Closer
+}
+
+func (b peekedBody) Close() error {
+	b.PipeReader.Close()
+	return b.body.Close()
+}
+
+// send tries the chain of model until an upstream accepts the request, a
+// retryable failure moves on to the next model as long as nothing has been
+// streamed. The last attempt is returned whatever its outcome.
+func (s *Service) send(c *gin.Context, cat Catalog, model string, rayReq RayChatRequest, stream bool) attempt {
+	ctx := c.Request.Context()
+	chain := s.fallbackChain(c, cat, model)
+	var a attempt
+	for i, candidate := range chain {
+		req := rayReq
+		if candidate != model {
+			req.Model, req.Provider = candidate, cat.Models[candidate]
+		}
+		a = attempt{model: candidate, upstream: s.upstream(cat.Upstream(candidate)), request: req}
+		start := time.Now()
+		a.res, a.err = a.upstream.Chat(ctx, req)
+		if a.err == nil {
+			metrics.UpstreamLatency.WithLabelValues(cat.ModelLabel(candidate)).Observe(time.Since(start).Seconds())
+		}
+		last := i == len(chain)-1
+		if a.err == nil && a.res.StatusCode == http.StatusOK {
+			if last {
+				return a
+			}
+			wait, timesOut := peekWait(stream)
+			peekStart := time.Now()
+			ok, err := peekError(a.res, wait)
+			a.waited = time.Since(peekStart)
+			switch {
+			case ok && err == nil, !ok && !timesOut:
+				// a good first event, or a heartbeat is due before one came and
+				// the stream goes on with this model
+				return a
+			case !ok:
+				a.err = errFirstByteTimeout
+			default:
+				a.err = err
+			}
+			// an error event or nothing in time, give up on this upstream
+			a.res.Body.Close()
+			a.res = nil
+		}
+		metrics.UpstreamErrors.WithLabelValues(cat.ModelLabel(candidate)).Inc()
+		if ctx.Err() != nil || last || !retryable(a.res, a.err) {
+			return a
+		}
+		requestLogger(c).Warnf("%s failed: %s, falling back to %s", a, a.failure(), chain[i+1])
+		s.upstreamFailed(a.upstream, a.statusCode(), a.failure())
+		if a.res != nil {
+			a.res.Body.Close()
+		}
+	}
+	return a
+}
+
+func (a attempt) statusCode() int {
+	if a.res == nil {
+		return 0
+	}
+	return a.res.StatusCode
+}
+
+// failure describes a failed attempt for the log.
+func (a attempt) failure() string {
+	if a.err != nil {
+		return a.err.Error()
+	}
+	return a.res.Status
+}
+
+func (a attempt) String() string {
+	return fmt.Sprintf("%s via %s", a.model, a.upstream.Name())
+}
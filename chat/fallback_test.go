@@ -0,0 +1,62 @@
+package chat
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPeekError(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		wantErr bool
+	}{
+		{name: "text", body: ": ping\n\ndata: {\"text\":\"hi\"}\n\ndata: {\"text\":\"!\"}\n\n"},
+		{name: "error event", body: "data: {\"error\":\"model overloaded\"}\n\n", wantErr: true},
+		{name: "empty", body: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := &http.Response{Body: io.NopCloser(strings.NewReader(tt.body))}
+			ok, err := peekError(res, 0)
+			if !ok || (err != nil) != tt.wantErr {
+				t.Fatalf("got %v, %v, want an error: %v", ok, err, tt.wantErr)
+			}
+			defer res.Body.Close()
+			if tt.wantErr {
+				return
+			}
+			got, err := io.ReadAll(res.Body)
+			if err != nil || string(got) != tt.body {
+				t.Errorf("body = %q, %v, want %q", got, err, tt.body)
+			}
+		})
+	}
+}
+
+// TestPeekErrorGivesUp checks that a silent upstream doesn't hold the
+// stream up and that nothing sent later is lost.
+func TestPeekErrorGivesUp(t *testing.T) {
+	pr, pw := io.Pipe()
+	res := &http.Response{Body: pr}
+	start := time.Now()
+	ok, err := peekError(res, 20*time.Millisecond)
+	if ok || err != nil {
+		t.Fatalf("got %v, %v, want to give up", ok, err)
+	}
+	if waited := time.Since(start); waited > time.Second {
+		t.Errorf("waited %v", waited)
+	}
+	go func() {
+		io.WriteString(pw, "data: {\"text\":\"late\"}\n\n")
+		pw.Close()
+	}()
+	got, _ := io.ReadAll(res.Body)
+	if string(got) != "data: {\"text\":\"late\"}\n\n" {
+		t.Errorf("body = %q", got)
+	}
+	res.Body.Close()
+}
@@ -4,6 +4,9 @@ import (
 	"crypto/rand"
 	"math/big"
 
+	"github.com/Ken288yzs1/raychat/logging"
+
+	"github.com/gin-gonic/gin"
 	"github.com/sirupsen/logrus"
 )
 
@@ -21,3 +24,7 @@ func generateRandomString(length int) string {
 func Logger() *logrus.Entry {
 	return logrus.WithField("prefix", "chat")
 }
+
+func requestLogger(c *gin.Context) *logrus.Entry {
+	return Logger().WithField("request_id", logging.GetRequestID(c))
+}
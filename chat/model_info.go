@@ -1,28 +1,37 @@
 package chat
 
 import (
-	"github.com/imroc/req/v3"
-)
+	"context"
+	"fmt"
 
-func (r *RayChat) GetSupportedModels() map[string]string {
-	c := req.C().SetCommonHeaders(map[string]string{
-		"Accept":          "application/json",
-		"Accept-Language": "zh-CN,zh-Hans;q=0.9",
-		"User-Agent":      "Raycast/0 CFNetwork/1408.0.4 Darwin/22.5.0",
-		"Content-Type":    "application/json",
-		"Authorization":   "Bearer " + r.Token,
-	}).DevMode()
+	"github.com/Ken288yzs1/raychat/metrics"
+	"github.com/Ken288yzs1/raychat/raycast"
+)
 
-	resp := GetAIInfoResponse{}
+// GetSupportedModels is GetModelInfo recording the refresh in the metrics.
+func (r *RayChat) GetSupportedModels(ctx context.Context) (GetAIInfoResponse, error) {
+	resp, err := r.GetModelInfo(ctx)
+	metrics.ModelRefreshes.WithLabelValues(metrics.Result(err)).Inc()
+	if err != nil {
+		return resp, err
+	}
+	Logger().Infof("get model info success, support those models: [%+v]", resp.SupportedModels())
+	Logger().Debugf("model info resp: [%+v]", resp)
+	metrics.ModelRefreshTimestamp.SetToCurrentTime()
+	metrics.ModelsAvailable.Set(float64(len(resp.Models)))
+	return resp, nil
+}
 
-	res, err := c.R().SetSuccessResult(&resp).Get("https://backend.raycast.com/api/v1/ai/models")
+// GetModelInfo returns the full catalog, including the models' context size,
+// scores and features.
+func (r *RayChat) GetModelInfo(ctx context.Context) (GetAIInfoResponse, error) {
+	req, err := raycast.NewModelsRequest(ctx, r.Token)
 	if err != nil {
-		Logger().WithError(err).Panic("get model info failed")
+		return GetAIInfoResponse{}, err
 	}
-	if res.StatusCode != 200 {
-		Logger().WithField("status code", res.StatusCode).Panic("get model info failed")
+	res, err := r.do(req, "models", nil)
+	if err != nil {
+		return GetAIInfoResponse{}, fmt.Errorf("get model info failed: %w", err)
 	}
-	Logger().Infof("get model info success, support those models: [%+v], resp: [%+v]", resp.SupporedModels(), resp)
-
-	return resp.SupporedModels()
+	return raycast.DecodeModels(res)
 }
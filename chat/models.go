@@ -0,0 +1,53 @@
+package chat
+
+import (
+	"net/http"
+	"sort"
+
+	"github.com/Ken288yzs1/raychat/keys"
+
+	"github.com/gin-gonic/gin"
+)
+
+type OpenAIModel struct {
+	ID      string `json:"id"`
+	Object  string `json:"object"`
+	Created int64  `json:"created"`
+	OwnedBy string `json:"owned_by"`
+}
+
+type OpenAIModelList struct {
+	Object string        `json:"object"`
+	Data   []OpenAIModel `json:"data"`
+}
+
+// ModelsEndpoint lists the auto models, then the models of the live
+// catalog, raycast's and those of every provider, leaving out the ones the
+// key may not use. An auto model is listed while it has a model to pick.
+func (s *Service) ModelsEndpoint(c *gin.Context) {
+	cat := s.catalog()
+	s.state.mu.RLock()
+	created := s.state.modelsLoaded.Unix()
+	s.state.mu.RUnlock()
+
+	var key *keys.Key
+	if k, ok := keys.FromContext(c); ok {
+		key = &k
+	}
+	list := OpenAIModelList{Object: "list", Data: []OpenAIModel{}}
+	for _, auto := range []string{AutoModel, AutoFastModel, AutoSmartModel} {
+		if len(cat.autoCandidates(auto, autoNeeds{}, key, s.quotas)) > 0 {
+			list.Data = append(list.Data, OpenAIModel{ID: auto, Object: "model", Created: created, OwnedBy: "raychat"})
+		}
+	}
+	models := []OpenAIModel{}
+	for model, provider := range cat.Models {
+		if key != nil && !key.AllowsModel(model) {
+			continue
+		}
+		models = append(models, OpenAIModel{ID: model, Object: "model", Created: created, OwnedBy: provider})
+	}
+	sort.Slice(models, func(i, j int) bool { return models[i].ID < models[j].ID })
+	list.Data = append(list.Data, models...)
+	c.JSON(http.StatusOK, list)
+}
@@ -0,0 +1,202 @@
+package chat
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/Ken288yzs1/raychat/settings"
+	"github.com/Ken288yzs1/raychat/transport"
+)
+
+// OpenAIUpstream is any backend with an openai compatible chat completions
+// api, e.g. vllm, ollama or openai itself.
+type OpenAIUpstream struct {
+	name    string
+	baseURL string
+	apiKey  string
+	models  []string
+	client  *http.Client
+}
+
+func NewOpenAIUpstream(p settings.Provider) *OpenAIUpstream {
+	return &OpenAIUpstream{
+		name:    p.Name,
+		baseURL: strings.TrimSuffix(p.BaseURL, "/"),
+		apiKey:  p.APIKey,
+		models:  p.Models,
+		// UPSTREAM_PROXY is meant for raycast, self-hosted backends are
+		// usually next door
+		client: transport.NewClient(http.ProxyFromEnvironment),
+	}
+}
+
+func (u *OpenAIUpstream) Name() string {
+	return u.name
+}
+
+// Models returns the configured models, or asks the backend when there are
+// none.
+func (u *OpenAIUpstream) Models(ctx context.Context) ([]ModelInfo, error) {
+	ids := u.models
+	if len(ids) == 0 {
+		var err error
+		if ids, err = u.fetchModels(ctx); err != nil {
+			return nil, err
+		}
+	}
+	models := make([]ModelInfo, 0, len(ids))
+	for _, id := range ids {
+		models = append(models, ModelInfo{
+			ID:           id,
+			Name:         id,
+			Model:        id,
+			Provider:     u.name,
+			ProviderName: u.name,
+		})
+	}
+	return models, nil
+}
+
+func (u *OpenAIUpstream) fetchModels(ctx context.Context) ([]string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.baseURL+"/models", nil)
+	if err != nil {
+		return nil, err
+	}
+	u.setHeaders(req)
+	res, err := u.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("list %s models failed: %w", u.name, err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("list %s models failed: %s", u.name, res.Status)
+	}
+	var list struct {
+		Data []struct {
+			ID string `json:"id"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&list); err != nil {
+		return nil, fmt.Errorf("decode %s models failed: %w", u.name, err)
+	}
+	ids := make([]string, 0, len(list.Data))
+	for _, m := range list.Data {
+		ids = append(ids, m.ID)
+	}
+	return ids, nil
+}
+
+// Chat translates the raycast request back to openai and the answer to
+// raycast events, error responses are passed through untouched.
+func (u *OpenAIUpstream) Chat(ctx context.Context, request RayChatRequest) (*http.Response, error) {
+	messages := make([]OpenAIStrMessage, 0, len(request.Messages)+1)
+	if request.AdditionalSystemInstructions != "" {
+		messages = append(messages, OpenAIStrMessage{Role: "system", Content: request.AdditionalSystemInstructions})
+	}
+	for _, m := range request.Messages {
+		messages = append(messages, OpenAIStrMessage{Role: m.Author, Content: m.Content.Text})
+	}
+	raw, err := json.Marshal(map[string]any{
+		"model":       request.Model,
+		"messages":    messages,
+		"temperature": request.Temperature,
+		"stream":      true,
+	})
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u.baseURL+"/chat/completions", bytes.NewReader(raw))
+	if err != nil {
+		return nil, err
+	}
+	u.setHeaders(req)
+	req.Header.Set("Content-Type", "application/json")
+
+	res, err := u.client.Do(req)
+	if err != nil || res.StatusCode != http.StatusOK {
+		return res, err
+	}
+	res.Body = &openAIEventBody{src: res.Body, scanner: bufio.NewScanner(res.Body)}
+	return res, nil
+}
+
+func (u *OpenAIUpstream) setHeaders(req *http.Request) {
+	req.Header.Set("Accept", "application/json, text/event-stream")
+	if u.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+u.apiKey)
+	}
+}
+
+// openAIEventBody turns an openai chunk stream into raycast events as it
+// is read.
+type openAIEventBody struct {
+	src     io.ReadCloser
+	scanner *bufio.Scanner
+	buf     bytes.Buffer
+}
+
+// rayEvent is what raycast sends, without the error field unless there is
+// an error
+type rayEvent struct {
+	Text         string  `json:"text"`
+	Reasoning    string  `json:"reasoning,omitempty"`
+	FinishReason *string `json:"finish_reason"`
+	Error        any     `json:"error,omitempty"`
+}
+
+func (b *openAIEventBody) Read(p []byte) (int, error) {
+	for b.buf.Len() == 0 {
+		if !b.scanner.Scan() {
+			if err := b.scanner.Err(); err != nil {
+				return 0, err
+			}
+			return 0, io.EOF
+		}
+		b.translate(b.scanner.Text())
+	}
+	return b.buf.Read(p)
+}
+
+func (b *openAIEventBody) translate(line string) {
+	data, ok := strings.CutPrefix(line, "data:")
+	data = strings.TrimSpace(data)
+	if !ok || data == "" || data == "[DONE]" {
+		return
+	}
+	var chunk struct {
+		Choices []struct {
+			Delta struct {
+				Content          string `json:"content"`
+				ReasoningContent string `json:"reasoning_content"`
+			} `json:"delta"`
+			FinishReason *string `json:"finish_reason"`
+		} `json:"choices"`
+		Error any `json:"error"`
+	}
+	event := rayEvent{}
+	if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+		event.Error = "decode upstream chunk: " + err.Error()
+	} else if chunk.Error != nil {
+		event.Error = chunk.Error
+	} else if len(chunk.Choices) > 0 {
+		choice := chunk.Choices[0]
+		event.Text, event.Reasoning, event.FinishReason = choice.Delta.Content, choice.Delta.ReasoningContent, choice.FinishReason
+	}
+	if event.Text == "" && event.Reasoning == "" && event.FinishReason == nil && event.Error == nil {
+		return
+	}
+	raw, _ := json.Marshal(event)
+	b.buf.WriteString("data: ")
+	b.buf.Write(raw)
+	b.buf.WriteString("\n\n")
+}
+
+func (b *openAIEventBody) Close() error {
+	return b.src.Close()
+}
@@ -0,0 +1,72 @@
+package chat
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const openAIFixturesDir = "testdata/openai"
+
+// TestOpenAIEventBody translates each openai chunk stream into raycast
+// events, compares them with the golden file and reads them back the way
+// the chat endpoint does.
+func TestOpenAIEventBody(t *testing.T) {
+	tests := []struct {
+		name     string
+		wantText string
+		wantErr  string
+	}{
+		{name: "text", wantText: "Hello there"},
+		{name: "reasoning", wantText: "Hi!"},
+		{name: "upstream-error", wantText: "Hel", wantErr: "model overloaded"},
+		{name: "malformed", wantText: "Hel", wantErr: "decode upstream chunk"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src, err := os.Open(filepath.Join(openAIFixturesDir, tt.name+".sse"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			body := &openAIEventBody{src: src, scanner: bufio.NewScanner(src)}
+			defer body.Close()
+			out, err := io.ReadAll(body)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			golden := filepath.Join(openAIFixturesDir, tt.name+".golden")
+			if *update {
+				if err := os.WriteFile(golden, out, 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(out) != string(want) {
+				t.Errorf("got\n%s\nwant\n%s", out, want)
+			}
+
+			var text strings.Builder
+			err = ReadStream(bytes.NewReader(out), func(r RayChatStreamResponse) error {
+				text.WriteString(r.Text)
+				return nil
+			})
+			if tt.wantErr == "" && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Fatalf("error = %v, want one containing %q", err, tt.wantErr)
+			}
+			if text.String() != tt.wantText {
+				t.Errorf("text = %q, want %q", text.String(), tt.wantText)
+			}
+		})
+	}
+}
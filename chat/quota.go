@@ -0,0 +1,45 @@
+package chat
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/Ken288yzs1/raychat/keys"
+	"github.com/Ken288yzs1/raychat/metrics"
+
+	"github.com/gin-gonic/gin"
+)
+
+// applyQuota enforces the key's quotas on the requested model, following
+// downgrade_to while quotas are spent. It answers the request itself and
+// returns false when nothing is left to fall back on.
+func (s *Service) applyQuota(c *gin.Context, cat Catalog, key keys.Key, req *OpenAIRequest) (string, bool) {
+	model, _ := req.GetRequestModel(cat)
+	requested := model
+	seen := map[string]bool{}
+	for {
+		status := s.quotas.Check(key, model)
+		if status == nil {
+			break
+		}
+		seen[model] = true
+		next := status.Quota.DowngradeTo
+		if next == "" || seen[next] {
+			metrics.Rejections.WithLabelValues("quota").Inc()
+			c.JSON(http.StatusTooManyRequests, gin.H{"error": gin.H{
+				"message": fmt.Sprintf("%s quota for %s exceeded, %d requests and %d tokens used since %s",
+					status.Quota.Period, model, status.Requests, status.Tokens, status.Since.Format("2006-01-02")),
+				"type": "insufficient_quota",
+				"code": "insufficient_quota",
+			}})
+			return "", false
+		}
+		Logger().WithField("key_id", key.ID).Infof("%s quota for %s exceeded, downgrade to %s", status.Quota.Period, model, next)
+		req.Model = next
+		model, _ = req.GetRequestModel(cat)
+	}
+	if model != requested {
+		c.Header("X-Model-Downgraded-From", requested)
+	}
+	return model, true
+}
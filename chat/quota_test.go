@@ -0,0 +1,69 @@
+package chat
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Ken288yzs1/raychat/keys"
+	"github.com/Ken288yzs1/raychat/quota"
+	"github.com/Ken288yzs1/raychat/usage"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestApplyQuota(t *testing.T) {
+	cat := Catalog{Models: map[string]string{"o3": "openai", "gpt-4o": "openai", "gpt-3.5-turbo": "openai"}}
+	spentO3 := keys.Quota{Period: "day", MaxRequests: 1, Models: []string{"o3"}, DowngradeTo: "gpt-4o"}
+	tests := []struct {
+		name           string
+		quotas         []keys.Quota
+		wantModel      string
+		wantStatus     int
+		wantDowngraded string
+	}{
+		{name: "no quota", wantModel: "o3", wantStatus: http.StatusOK},
+		{name: "downgrade", quotas: []keys.Quota{spentO3}, wantModel: "gpt-4o", wantStatus: http.StatusOK, wantDowngraded: "o3"},
+		{
+			name: "downgrade target spent too",
+			quotas: []keys.Quota{spentO3,
+				{Period: "day", MaxRequests: 1, Models: []string{"gpt-4o"}}},
+			wantStatus: http.StatusTooManyRequests,
+		},
+		{
+			name: "downgrade loop",
+			quotas: []keys.Quota{spentO3,
+				{Period: "day", MaxRequests: 1, Models: []string{"gpt-4o"}, DowngradeTo: "o3"}},
+			wantStatus: http.StatusTooManyRequests,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ledger, err := usage.Open("", 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			s := &Service{quotas: quota.New(ledger)}
+			key := keys.Key{ID: "key_1", Quotas: tt.quotas}
+			// spend one request on every model
+			for model := range cat.Models {
+				s.quotas.Record(key, usage.Record{Time: time.Now(), KeyID: key.ID, Model: model})
+			}
+
+			rec := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(rec)
+			req := &OpenAIRequest{Model: "o3"}
+			model, ok := s.applyQuota(c, cat, key, req)
+			if ok != (tt.wantStatus == http.StatusOK) || rec.Code != tt.wantStatus {
+				t.Fatalf("ok = %v, status %d, want %d", ok, rec.Code, tt.wantStatus)
+			}
+			if model != tt.wantModel {
+				t.Errorf("model = %q, want %q", model, tt.wantModel)
+			}
+			if got := rec.Header().Get("X-Model-Downgraded-From"); got != tt.wantDowngraded {
+				t.Errorf("X-Model-Downgraded-From = %q, want %q", got, tt.wantDowngraded)
+			}
+		})
+	}
+}
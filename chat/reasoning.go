@@ -0,0 +1,79 @@
+package chat
+
+import (
+	"github.com/Ken288yzs1/raychat/keys"
+	"github.com/Ken288yzs1/raychat/settings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ReasoningFormat is defined by keys, which the config and the admin api
+// check formats with as well.
+type ReasoningFormat = keys.ReasoningFormat
+
+const (
+	ReasoningField = keys.ReasoningField
+	ReasoningThink = keys.ReasoningThink
+	ReasoningHide  = keys.ReasoningHide
+)
+
+// requestReasoningFormat reads the format from the X-Reasoning-Format header
+// or the reasoning_format query param, then the api key's own setting, falling
+// back to REASONING_FORMAT.
+func requestReasoningFormat(c *gin.Context) ReasoningFormat {
+	key, _ := keys.FromContext(c)
+	for _, s := range []string{c.GetHeader("X-Reasoning-Format"), c.Query("reasoning_format"), key.ReasoningFormat} {
+		if f, ok := keys.ParseReasoningFormat(s); ok {
+			return f
+		}
+	}
+	if f, ok := keys.ParseReasoningFormat(settings.Get().ReasoningFormat); ok {
+		return f
+	}
+	return ReasoningField
+}
+
+// reasoningRenderer rewrites raycast chunks according to the format, it keeps
+// track of whether a <think> block is still open across chunks.
+type reasoningRenderer struct {
+	format ReasoningFormat
+	open   bool
+}
+
+func newReasoningRenderer(format ReasoningFormat) *reasoningRenderer {
+	return &reasoningRenderer{format: format}
+}
+
+func (r *reasoningRenderer) Render(resp RayChatStreamResponse) RayChatStreamResponse {
+	switch r.format {
+	case ReasoningHide:
+		resp.Reasoning = ""
+	case ReasoningThink:
+		text := ""
+		if resp.Reasoning != "" {
+			if !r.open {
+				text += "<think>\n"
+				r.open = true
+			}
+			text += resp.Reasoning
+		}
+		if resp.Text != "" {
+			text += r.Close()
+		}
+		resp.Text = text + resp.Text
+		resp.Reasoning = ""
+	}
+	if resp.FinishReason != nil {
+		resp.Text += r.Close()
+	}
+	return resp
+}
+
+// Close ends a dangling <think> block, it returns what has to be appended.
+func (r *reasoningRenderer) Close() string {
+	if !r.open {
+		return ""
+	}
+	r.open = false
+	return "\n</think>\n\n"
+}
@@ -0,0 +1,133 @@
+package chat
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestReasoningRenderer(t *testing.T) {
+	stop := "stop"
+	reasoningThenText := []RayChatStreamResponse{{Reasoning: "a"}, {Reasoning: "b"}, {Text: "hi"}, {FinishReason: &stop}}
+	reasoningOnly := []RayChatStreamResponse{{Reasoning: "a"}, {FinishReason: &stop}}
+
+	type chunk struct {
+		text, reasoning string
+		finish          bool
+	}
+	tests := []struct {
+		name      string
+		format    ReasoningFormat
+		in        []RayChatStreamResponse
+		want      []chunk
+		wantClose string
+	}{
+		{
+			name:   "field keeps reasoning apart",
+			format: ReasoningField,
+			in:     reasoningThenText,
+			want:   []chunk{{reasoning: "a"}, {reasoning: "b"}, {text: "hi"}, {finish: true}},
+		},
+		{
+			name:   "think reasoning then text",
+			format: ReasoningThink,
+			in:     reasoningThenText,
+			want:   []chunk{{text: "<think>\na"}, {text: "b"}, {text: "\n</think>\n\nhi"}, {finish: true}},
+		},
+		{
+			name:   "think reasoning only then finish",
+			format: ReasoningThink,
+			in:     reasoningOnly,
+			want:   []chunk{{text: "<think>\na"}, {text: "\n</think>\n\n", finish: true}},
+		},
+		{
+			name:   "hide",
+			format: ReasoningHide,
+			in:     reasoningThenText,
+			want:   []chunk{{}, {}, {text: "hi"}, {finish: true}},
+		},
+		{
+			// plain responses end without a finish chunk, plainResp closes them
+			name:      "think plain",
+			format:    ReasoningThink,
+			in:        []RayChatStreamResponse{{Reasoning: "a"}},
+			want:      []chunk{{text: "<think>\na"}},
+			wantClose: "\n</think>\n\n",
+		},
+		{
+			name:   "think plain closed by text",
+			format: ReasoningThink,
+			in:     []RayChatStreamResponse{{Reasoning: "a"}, {Text: "hi"}},
+			want:   []chunk{{text: "<think>\na"}, {text: "\n</think>\n\nhi"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newReasoningRenderer(tt.format)
+			for i, in := range tt.in {
+				out := r.Render(in)
+				got := chunk{text: out.Text, reasoning: out.Reasoning, finish: out.FinishReason != nil}
+				if got != tt.want[i] {
+					t.Errorf("chunk %d = %+v, want %+v", i, got, tt.want[i])
+				}
+			}
+			if got := r.Close(); got != tt.wantClose {
+				t.Errorf("Close() = %q, want %q", got, tt.wantClose)
+			}
+		})
+	}
+}
+
+func TestStreamRespHide(t *testing.T) {
+	res, err := replay(fixturesDir, "reasoning", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(rec)
+	c.Request = httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil)
+
+	meta := NewCompletionMeta("test-model")
+	streamResp(c, meta, newReasoningRenderer(ReasoningHide), res, 0)
+
+	body := rec.Body.String()
+	if strings.Contains(body, "reasoning") {
+		t.Errorf("reasoning leaked: %s", body)
+	}
+	// the role chunk, Hi!, the finish chunk and [DONE], each one event
+	events := strings.Split(strings.TrimSuffix(body, "\n\n"), "\n\n")
+	if len(events) != 4 {
+		t.Fatalf("got %d events: %q", len(events), body)
+	}
+	for _, e := range events {
+		if !strings.HasPrefix(e, "data: ") || strings.Contains(e, "\n") {
+			t.Errorf("malformed event %q", e)
+		}
+	}
+}
+
+func TestPlainRespThink(t *testing.T) {
+	res, err := replay(fixturesDir, "reasoning", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(rec)
+	c.Request = httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil)
+
+	meta := NewCompletionMeta("test-model")
+	plainResp(c, meta, newReasoningRenderer(ReasoningThink), res)
+
+	var got OpenAIResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	want := "<think>\nThe user greets me. Answer briefly.\n</think>\n\nHi!"
+	if content := got.Choices[0].Message.Content; content != want {
+		t.Errorf("content = %q, want %q", content, want)
+	}
+}
@@ -0,0 +1,161 @@
+package chat
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/Ken288yzs1/raychat/settings"
+)
+
+const (
+	UpstreamLive   = "live"
+	UpstreamRecord = "record"
+	UpstreamReplay = "replay"
+)
+
+// fixtureMeta is stored next to the raw upstream body of a recording.
+type fixtureMeta struct {
+	Request    any    `json:"request,omitempty"`
+	StatusCode int    `json:"status_code"`
+	Complete   bool   `json:"complete"`
+	Hash       string `json:"hash"`
+}
+
+// requestHash names the fixture of a chat request. Only what changes the
+// answer goes into it, and whitespace around messages is ignored.
+func requestHash(request RayChatRequest) string {
+	normalized := request
+	normalized.Debug = false
+	normalized.Locale = ""
+	normalized.Messages = make([]RayChatMessage, len(request.Messages))
+	for i, m := range request.Messages {
+		m.Content.Text = strings.TrimSpace(m.Content.Text)
+		normalized.Messages[i] = m
+	}
+	normalized.AdditionalSystemInstructions = strings.TrimSpace(request.AdditionalSystemInstructions)
+	raw, _ := json.Marshal(normalized)
+	sum := sha256.Sum256(raw)
+	return hex.EncodeToString(sum[:8])
+}
+
+// do sends req according to UPSTREAM_MODE: live, recording the raw response
+// under name, or answering from the recording without touching raycast.
+func (r *RayChat) do(req *http.Request, name string, payload any) (*http.Response, error) {
+	conf := settings.Get()
+	switch conf.UpstreamMode {
+	case UpstreamReplay:
+		return replay(conf.FixturesDir, name, req)
+	case UpstreamRecord:
+		res, err := r.httpClient().Do(req)
+		if err != nil {
+			return res, err
+		}
+		res.Body = &recordingBody{
+			body: res.Body,
+			dir:  conf.FixturesDir,
+			name: name,
+			meta: fixtureMeta{Request: payload, StatusCode: res.StatusCode, Hash: name},
+		}
+		return res, nil
+	default:
+		return r.httpClient().Do(req)
+	}
+}
+
+func replay(dir, name string, req *http.Request) (*http.Response, error) {
+	rawMeta, err := os.ReadFile(filepath.Join(dir, name+".json"))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("no fixture recorded for request %s in %s", name, dir)
+	}
+	if err != nil {
+		return nil, err
+	}
+	meta := fixtureMeta{}
+	if err := json.Unmarshal(rawMeta, &meta); err != nil {
+		return nil, err
+	}
+	body, err := os.ReadFile(filepath.Join(dir, name+".sse"))
+	if err != nil {
+		return nil, err
+	}
+	Logger().Debugf("replay fixture %s", name)
+	return &http.Response{
+		Status:     fmt.Sprintf("%d %s", meta.StatusCode, http.StatusText(meta.StatusCode)),
+		StatusCode: meta.StatusCode,
+		Proto:      "HTTP/1.1",
+		ProtoMajor: 1,
+		ProtoMinor: 1,
+		Header:     http.Header{"Content-Type": {"text/event-stream"}},
+		Body:       io.NopCloser(bytes.NewReader(body)),
+		Request:    req,
+	}, nil
+}
+
+// recordingBody keeps a copy of everything read from the upstream body and
+// writes it out as a fixture when the body is closed. Reads come from the
+// stream's reader goroutine while Close may come from the handler on a
+// timeout, mu keeps the two apart.
+type recordingBody struct {
+	body io.ReadCloser
+	dir  string
+	name string
+
+	mu     sync.Mutex
+	buf    bytes.Buffer
+	meta   fixtureMeta
+	closed bool
+}
+
+func (b *recordingBody) Read(p []byte) (int, error) {
+	n, err := b.body.Read(p)
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !b.closed {
+		b.buf.Write(p[:n])
+		if err == io.EOF {
+			b.meta.Complete = true
+		}
+	}
+	return n, err
+}
+
+func (b *recordingBody) Close() error {
+	err := b.body.Close()
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return err
+	}
+	b.closed = true
+	if werr := b.write(); werr != nil {
+		Logger().WithError(werr).Errorf("write fixture %s failed", b.name)
+	} else {
+		Logger().Infof("recorded fixture %s, %d bytes, complete: %v", b.name, b.buf.Len(), b.meta.Complete)
+	}
+	return err
+}
+
+// write saves the fixture, owner only since it holds prompts.
+func (b *recordingBody) write() error {
+	if err := os.MkdirAll(b.dir, 0o700); err != nil {
+		return err
+	}
+	rawMeta, err := json.MarshalIndent(b.meta, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(b.dir, b.name+".json"), rawMeta, 0o600); err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(b.dir, b.name+".sse"), b.buf.Bytes(), 0o600)
+}
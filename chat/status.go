@@ -0,0 +1,134 @@
+package chat
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// upstreamState is what the probes and /status report about raycast.
+type upstreamState struct {
+	mu sync.RWMutex
+
+	startedAt      time.Time
+	tokenSource    string
+	tokenObtained  time.Time
+	tokenValidated bool
+	modelsLoaded   time.Time
+	modelCount     int
+
+	lastSuccess     time.Time
+	lastError       time.Time
+	lastErrorMsg    string
+	lastErrorStatus int
+}
+
+func newUpstreamState() *upstreamState {
+	return &upstreamState{startedAt: time.Now()}
+}
+
+func (s *upstreamState) tokenReady(source string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tokenSource, s.tokenObtained = source, time.Now()
+}
+
+// modelsReady also proves the token works, the catalog can't be fetched without it.
+func (s *upstreamState) modelsReady(count int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.modelsLoaded, s.modelCount, s.tokenValidated = time.Now(), count, true
+}
+
+func (s *upstreamState) upstreamSucceeded() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lastSuccess = time.Now()
+}
+
+func (s *upstreamState) upstreamFailed(status int, msg string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lastError, s.lastErrorStatus, s.lastErrorMsg = time.Now(), status, msg
+}
+
+// accountHealthy is false once raycast rejected the token and nothing has
+// succeeded since.
+func (s *upstreamState) accountHealthy() bool {
+	rejected := s.lastErrorStatus == http.StatusUnauthorized || s.lastErrorStatus == http.StatusForbidden
+	return !(rejected && s.lastError.After(s.lastSuccess))
+}
+
+func (s *upstreamState) checks() map[string]bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return map[string]bool{
+		"token":   s.tokenSource != "" && s.tokenValidated,
+		"models":  s.modelCount > 0,
+		"account": s.accountHealthy(),
+	}
+}
+
+// HealthzEndpoint only says the process is serving.
+func (s *Service) HealthzEndpoint(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"status": "ok"})
+}
+
+// ReadyzEndpoint fails until a validated token and the model catalog are
+// there, and while raycast keeps rejecting the account.
+func (s *Service) ReadyzEndpoint(c *gin.Context) {
+	checks := s.state.checks()
+	ready := true
+	for _, ok := range checks {
+		ready = ready && ok
+	}
+	status := http.StatusOK
+	if !ready {
+		status = http.StatusServiceUnavailable
+	}
+	c.JSON(status, gin.H{"ready": ready, "checks": checks})
+}
+
+func (s *Service) StatusEndpoint(c *gin.Context) {
+	state := s.state
+	checks := state.checks()
+	user := s.catalog().User
+
+	state.mu.RLock()
+	defer state.mu.RUnlock()
+	resp := gin.H{
+		"checks":         checks,
+		"uptime_seconds": int(time.Since(state.startedAt).Seconds()),
+		"user": gin.H{
+			"handle":                   user.Handle,
+			"has_active_subscription":  user.HasActiveSubscription,
+			"has_running_subscription": user.HasRunningSubscription,
+			"eligible_for_gpt4":        user.EligibleForGpt4,
+			"admin":                    user.Admin,
+		},
+		"token": gin.H{
+			"source":      state.tokenSource,
+			"age_seconds": int(time.Since(state.tokenObtained).Seconds()),
+			"validated":   state.tokenValidated,
+		},
+		"models": gin.H{
+			"count":     state.modelCount,
+			"loaded_at": state.modelsLoaded,
+		},
+		"last_upstream_success": nil,
+		"last_upstream_error":   nil,
+	}
+	if !state.lastSuccess.IsZero() {
+		resp["last_upstream_success"] = state.lastSuccess
+	}
+	if !state.lastError.IsZero() {
+		resp["last_upstream_error"] = gin.H{
+			"time":        state.lastError,
+			"status_code": state.lastErrorStatus,
+			"message":     state.lastErrorMsg,
+		}
+	}
+	c.JSON(http.StatusOK, resp)
+}
@@ -0,0 +1,114 @@
+package chat
+
+import (
+	"bufio"
+	"io"
+	"time"
+
+	"github.com/Ken288yzs1/raychat/raycast"
+)
+
+// ReadStream calls fn for every event of a raycast completion body. It is
+// for callers outside the server, which need no heartbeats or timeouts.
+func ReadStream(body io.Reader, fn func(RayChatStreamResponse) error) error {
+	return raycast.ReadEvents(body, func(event raycast.StreamEvent) error {
+		return fn(RayChatStreamResponse(event))
+	})
+}
+
+// ParseStreamEvent decodes one line of a raycast stream, ok is false for
+// the blank lines between events and sse comments. An error event or a line
+// that doesn't decode becomes an error.
+func ParseStreamEvent(line string) (RayChatStreamResponse, bool, error) {
+	event, ok, err := raycast.ParseEvent(line)
+	return RayChatStreamResponse(event), ok, err
+}
+
+// eventReader scans the raycast body on its own goroutine so that the
+// stream loop can wait on it together with heartbeats and timeouts.
+type eventReader struct {
+	events chan string
+	stop   chan struct{}
+	err    error
+}
+
+func newEventReader(body io.Reader) *eventReader {
+	r := &eventReader{
+		events: make(chan string),
+		stop:   make(chan struct{}),
+	}
+	go func() {
+		defer close(r.events)
+		scanner := bufio.NewScanner(body)
+		for scanner.Scan() {
+			select {
+			case r.events <- scanner.Text():
+			case <-r.stop:
+				return
+			}
+		}
+		r.err = scanner.Err()
+	}()
+	return r
+}
+
+// Err is only meaningful once Events has been drained.
+func (r *eventReader) Err() error {
+	return r.err
+}
+
+func (r *eventReader) Events() <-chan string {
+	return r.events
+}
+
+func (r *eventReader) Close() {
+	close(r.stop)
+}
+
+// idleTimer fires when raycast stays silent for too long, with a separate
+// allowance for the first event since reasoning models think before talking.
+type idleTimer struct {
+	timer    *time.Timer
+	first    time.Duration
+	idle     time.Duration
+	gotFirst bool
+}
+
+func newIdleTimer(first, idle time.Duration) *idleTimer {
+	t := &idleTimer{first: first, idle: idle}
+	if first > 0 {
+		t.timer = time.NewTimer(first)
+	}
+	return t
+}
+
+func (t *idleTimer) C() <-chan time.Time {
+	if t.timer == nil {
+		return nil
+	}
+	return t.timer.C
+}
+
+func (t *idleTimer) Reset() {
+	t.gotFirst = true
+	if t.timer != nil {
+		t.timer.Stop()
+		t.timer = nil
+	}
+	if t.idle > 0 {
+		t.timer = time.NewTimer(t.idle)
+	}
+}
+
+func (t *idleTimer) Phase() string {
+	if t.gotFirst {
+		return "idle"
+	}
+	return "first byte"
+}
+
+func (t *idleTimer) Stop() {
+	if t.timer != nil {
+		t.timer.Stop()
+	}
+}
@@ -0,0 +1,160 @@
+package chat
+
+import (
+	"bytes"
+	"flag"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files of the stream tests")
+
+const fixturesDir = "testdata/fixtures"
+
+// TestFixtureStreams replays each fixture through the stream parser and the
+// openai conversion and compares the result with its golden file.
+func TestFixtureStreams(t *testing.T) {
+	tests := []struct {
+		name    string
+		wantErr string
+	}{
+		{name: "text"},
+		{name: "reasoning"},
+		// the word error in the text must not drop the event
+		{name: "error-word"},
+		{name: "upstream-error", wantErr: "model overloaded"},
+		{name: "malformed", wantErr: "decode raycast event"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := replay(fixturesDir, tt.name, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer res.Body.Close()
+
+			meta := &CompletionMeta{ID: "chatcmpl-test", Model: "test-model"}
+			var out bytes.Buffer
+			err = ReadStream(res.Body, func(r RayChatStreamResponse) error {
+				out.WriteString(r.ToOpenAISteamResponse(meta).ToEventString() + "\n\n")
+				return nil
+			})
+			if tt.wantErr == "" && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Fatalf("error = %v, want one containing %q", err, tt.wantErr)
+			}
+
+			golden := filepath.Join(fixturesDir, tt.name+".golden")
+			if *update {
+				if err := os.WriteFile(golden, out.Bytes(), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if out.String() != string(want) {
+				t.Errorf("got\n%s\nwant\n%s", out.String(), want)
+			}
+		})
+	}
+}
+
+func TestParseStreamEvent(t *testing.T) {
+	tests := []struct {
+		name    string
+		line    string
+		ok      bool
+		text    string
+		wantErr bool
+	}{
+		{name: "blank", line: ""},
+		{name: "comment", line: ": ping"},
+		{name: "text", line: `data: {"text":"hi"}`, ok: true, text: "hi"},
+		{name: "empty error", line: `data: {"text":"hi","error":""}`, ok: true, text: "hi"},
+		{name: "null error", line: `data: {"text":"hi","error":null}`, ok: true, text: "hi"},
+		{name: "error", line: `data: {"error":"rate limited"}`, wantErr: true},
+		{name: "truncated", line: `data: {"text":"h`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, ok, err := ParseStreamEvent(tt.line)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, want error: %v", err, tt.wantErr)
+			}
+			if ok != tt.ok || resp.Text != tt.text {
+				t.Errorf("got %q, %v, want %q, %v", resp.Text, ok, tt.text, tt.ok)
+			}
+		})
+	}
+}
+
+// TestRecordingBody records a body and replays it.
+func TestRecordingBody(t *testing.T) {
+	dir := t.TempDir()
+	body, err := os.ReadFile(filepath.Join(fixturesDir, "reasoning.sse"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec := &recordingBody{
+		body: io.NopCloser(bytes.NewReader(body)),
+		dir:  dir,
+		name: "recorded",
+		meta: fixtureMeta{StatusCode: http.StatusOK, Hash: "recorded"},
+	}
+
+	if _, err := io.Copy(io.Discard, rec); err != nil {
+		t.Fatal(err)
+	}
+	rec.Close()
+	// a late close must not write the fixture again
+	rec.Close()
+
+	for _, ext := range []string{".json", ".sse"} {
+		info, err := os.Stat(filepath.Join(dir, "recorded"+ext))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if perm := info.Mode().Perm(); perm != 0o600 {
+			t.Errorf("%s mode = %v, want 0600", ext, perm)
+		}
+	}
+	res, err := replay(dir, "recorded", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	replayed, _ := io.ReadAll(res.Body)
+	if !bytes.Equal(replayed, body) {
+		t.Errorf("replayed %q, want %q", replayed, body)
+	}
+}
+
+// TestRecordingBodyCloseWhileReading closes the body while it is still
+// being read, like a timed out stream does. Run with -race.
+func TestRecordingBodyCloseWhileReading(t *testing.T) {
+	pr, pw := io.Pipe()
+	rec := &recordingBody{body: pr, dir: t.TempDir(), name: "cut"}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		io.Copy(io.Discard, rec)
+	}()
+	pw.Write([]byte("data: {\"text\":\"a\"}\n\n"))
+	rec.Close()
+	<-done
+
+	raw, err := os.ReadFile(filepath.Join(rec.dir, "cut.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(raw), `"complete": false`) {
+		t.Errorf("cut off recording marked complete: %s", raw)
+	}
+}
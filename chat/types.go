@@ -2,9 +2,11 @@ package chat
 
 import (
 	"encoding/json"
-	"raychat/auth"
 	"strings"
-	"time"
+
+	"github.com/Ken288yzs1/raychat/raycast"
+	"github.com/Ken288yzs1/raychat/settings"
+	"github.com/Ken288yzs1/raychat/usage"
 
 	"github.com/samber/lo"
 )
@@ -16,11 +18,12 @@ type UnTypedOpenAIMessage interface {
 }
 
 type OpenAIRequest struct {
-	Model       string                 `json:"model"`
-	Messages    []interface{}          `json:"messages"`
-	Stream      bool                   `json:"stream"`
-	Temperature float64                `json:"temperature"`
-	messages    []UnTypedOpenAIMessage `json:"-"`
+	Model       string        `json:"model"`
+	Messages    []interface{} `json:"messages"`
+	Stream      bool          `json:"stream"`
+	Temperature float64       `json:"temperature"`
+	// ReasoningEffort only tells auto models to pick a reasoning model
+	ReasoningEffort string `json:"reasoning_effort,omitempty"`
 }
 
 // func (r OpenAIRequest) ToStrOpenAIRequest() OpenAIRequest[string] {
@@ -40,8 +43,10 @@ type OpenAIRequest struct {
 
 // func GetStrOpenAIMessage()
 
-func (r OpenAIRequest) ToRayChatRequest(a *auth.RaycastAuth) RayChatRequest {
+func (r OpenAIRequest) ToRayChatRequest(cat Catalog) RayChatRequest {
 	messages := make([]RayChatMessage, 0, len(r.Messages))
+	// raycast takes the system prompt apart from the messages, the last one wins
+	system := ""
 	for _, m := range r.Messages {
 		var (
 			tmpMsg UnTypedOpenAIMessage
@@ -55,6 +60,7 @@ func (r OpenAIRequest) ToRayChatRequest(a *auth.RaycastAuth) RayChatRequest {
 			}
 		}
 		if tmpMsg.GetRole() == "system" {
+			system = tmpMsg.GetContent()
 			continue
 		}
 
@@ -64,133 +70,62 @@ func (r OpenAIRequest) ToRayChatRequest(a *auth.RaycastAuth) RayChatRequest {
 		r.Temperature = 1
 	}
 
-	model, provider := r.GetRequestModel(a)
-
-	resp := RayChatRequest{
-		Debug:             false,
-		Locale:            "en-CN",
-		Provider:          provider,
-		Model:             model,
-		Temperature:       r.Temperature,
-		SystemInstruction: "markdown",
-		Messages:          messages,
-	}
-
-	additionalSystemInstructions := r.GetSystemMessage().Content
-	if additionalSystemInstructions != "" {
-		resp.AdditionalSystemInstructions = additionalSystemInstructions
-	}
+	model, provider := r.GetRequestModel(cat)
 
+	resp := NewRayChatRequest(model, provider, messages, system)
+	resp.Temperature = r.Temperature
 	return resp
 }
 
-func (r OpenAIRequest) GetRequestModel(a *auth.RaycastAuth) (string, string) {
+// NewRayChatRequest builds a request the way the raycast app sends them,
+// system is optional.
+func NewRayChatRequest(model, provider string, messages []RayChatMessage, system string) RayChatRequest {
+	return RayChatRequest(raycast.NewChatRequest(model, provider, messages, system))
+}
+
+func (r OpenAIRequest) GetRequestModel(cat Catalog) (string, string) {
 	model := r.Model
-	supporedModels := lo.Keys(models)
-	for _, m := range a.LoginResp.User.AiChatModels {
+	if target, ok := settings.Get().ModelAliases[model]; ok {
+		model = target
+	}
+	supporedModels := lo.Keys(cat.Models)
+	for _, m := range cat.User.AiChatModels {
 		supporedModels = append(supporedModels, m.Model)
 	}
-	if a.LoginResp.User.EligibleForGpt4 {
+	if cat.User.EligibleForGpt4 {
 		supporedModels = append(supporedModels, "gpt-4")
 	}
 
-	if !lo.Contains(supporedModels, r.Model) {
+	// models routed to another upstream don't have to be in any catalog
+	if !lo.Contains(supporedModels, model) && cat.Upstream(model) == RaycastUpstream {
 		model = "gpt-3.5-turbo"
 	}
-	return model, models[model]
+	return model, cat.Models[model]
 }
 
-func (r OpenAIRequest) GetSystemMessage() OpenAIStrMessage {
-	additionalSystem := ""
-	for _, m := range r.messages {
-		if m.GetRole() == "system" {
-			additionalSystem = m.GetContent()
-		}
-	}
-	return OpenAIStrMessage{
-		Role:    "system",
-		Content: additionalSystem,
-	}
-}
-
-func (r OpenAIRequest) GetNoneSystemMessage() []UnTypedOpenAIMessage {
-	msgs := []UnTypedOpenAIMessage{}
-	for _, m := range r.messages {
-		if m.GetRole() != "system" {
-			msgs = append(msgs, m)
-		}
-	}
-	return msgs
-}
-
-type RayChatRequest struct {
-	Debug                        bool             `json:"debug"`
-	Locale                       string           `json:"locale"`
-	Messages                     []RayChatMessage `json:"messages"`
-	Source                       string           `json:"source"`
-	Provider                     string           `json:"provider"`
-	Model                        string           `json:"model"`
-	Temperature                  float64          `json:"temperature"`
-	SystemInstruction            string           `json:"system_instruction"`
-	AdditionalSystemInstructions string           `json:"additional_system_instructions,omitempty"`
-}
-
-type Content struct {
-	Text string `json:"text"`
-}
-
-type RayChatMessage struct {
-	Content Content `json:"content"`
-	Author  string  `json:"author"`
-}
-
-func (m RayChatMessage) ToOpenAIMessage() OpenAIStrMessage {
-	return OpenAIStrMessage{
-		Role:    m.Author,
-		Content: m.Content.Text,
-	}
-}
-
-type RayChatStreamResponse struct {
-	Text         string      `json:"text"`
-	Reasoning    string      `json:"reasoning"`
-	FinishReason *string     `json:"finish_reason"`
-	Err          interface{} `json:"error"`
-}
+// the raycast wire types, chat adds the openai conversions
+type (
+	Content               = raycast.Content
+	RayChatMessage        = raycast.Message
+	GetAIInfoResponse     = raycast.ModelsResponse
+	ModelInfo             = raycast.ModelInfo
+	RayChatRequest        raycast.ChatRequest
+	RayChatStreamResponse raycast.StreamEvent
+)
 
-func (r RayChatStreamResponse) FromEventString(origin string) RayChatStreamResponse {
-	selection := strings.Replace(origin, "data: ", "", 1)
-	if len(selection) == 0 {
-		return RayChatStreamResponse{}
-	}
-	err := json.Unmarshal([]byte(selection), &r)
-	if err != nil {
-		panic(err)
-	}
-	if strings.Contains(selection, "error") {
-		Logger().WithError(err).Errorf("request to raycast error, body: %+v", origin)
-		return RayChatStreamResponse{}
+// PromptTokens estimates the size of the prompt sent to raycast.
+func (r RayChatRequest) PromptTokens() int {
+	tokens := usage.Estimate(r.AdditionalSystemInstructions)
+	for _, m := range r.Messages {
+		tokens += usage.Estimate(m.Content.Text)
 	}
-	return r
+	return tokens
 }
 
-func (r RayChatStreamResponse) ToOpenAISteamResponse(model string) OpenAIStreamResponse {
-
-	resp := OpenAIStreamResponse{
-		ID:      "chatcmpl-" + generateRandomString(29),
-		Object:  "chat.completion.chunk",
-		Created: int(time.Now().Unix()),
-		Model:   model,
-		Choices: []StreamChoices{
-			{
-				Index:        0,
-				FinishReason: r.FinishReason,
-			},
-		},
-	}
+func (r RayChatStreamResponse) ToOpenAISteamResponse(meta *CompletionMeta) OpenAIStreamResponse {
+	resp := meta.chunk(r.FinishReason)
 	if len(r.Text) != 0 || len(r.Reasoning) != 0 {
 		resp.Choices[0].Delta = Delta{
-			Role:             "assistant",
 			Content:          r.Text,
 			ReasoningContent: r.Reasoning,
 		}
@@ -201,19 +136,32 @@ func (r RayChatStreamResponse) ToOpenAISteamResponse(model string) OpenAIStreamR
 
 type RayChatStreamResponses []RayChatStreamResponse
 
-func (r RayChatStreamResponses) ToOpenAIResponse(model string) OpenAIResponse {
-	content := ""
+// Join assembles the full text and reasoning of the completion.
+func (r RayChatStreamResponses) Join() (string, string) {
+	var content, reasoning strings.Builder
 	for _, resp := range r {
-		content += resp.Text
+		content.WriteString(resp.Text)
+		reasoning.WriteString(resp.Reasoning)
 	}
-	reasoning := ""
+	return content.String(), reasoning.String()
+}
+
+func (r RayChatStreamResponses) Tokens(promptTokens int) usage.Tokens {
+	t := usage.Tokens{Prompt: promptTokens}
 	for _, resp := range r {
-		reasoning += resp.Reasoning
+		t.Completion += usage.Estimate(resp.Text)
+		t.Reasoning += usage.Estimate(resp.Reasoning)
 	}
+	return t
+}
+
+func (r RayChatStreamResponses) ToOpenAIResponse(meta *CompletionMeta, tokens usage.Tokens) OpenAIResponse {
+	content, reasoning := r.Join()
 	return OpenAIResponse{
-		ID:      "chatcmpl-" + generateRandomString(29),
-		Object:  "chat.completion",
-		Created: int(time.Now().Unix()),
+		ID:                meta.ID,
+		Object:            "chat.completion",
+		Created:           meta.Created,
+		SystemFingerprint: systemFingerprint,
 		Choices: []Choices{
 			{
 				Index: 0,
@@ -225,22 +173,23 @@ func (r RayChatStreamResponses) ToOpenAIResponse(model string) OpenAIResponse {
 				FinishReason: lo.ToPtr("stop"),
 			},
 		},
-		Model: model,
+		Model: meta.Model,
 		Usage: Usage{
-			PromptTokens:     0,
-			CompletionTokens: 0,
-			TotalTokens:      0,
+			PromptTokens:     tokens.Prompt,
+			CompletionTokens: tokens.Completion + tokens.Reasoning,
+			TotalTokens:      tokens.Total(),
 		},
 	}
 }
 
 type OpenAIResponse struct {
-	ID      string    `json:"id"`
-	Object  string    `json:"object"`
-	Created int       `json:"created"`
-	Model   string    `json:"model"`
-	Choices []Choices `json:"choices"`
-	Usage   Usage     `json:"usage"`
+	ID                string    `json:"id"`
+	Object            string    `json:"object"`
+	Created           int       `json:"created"`
+	Model             string    `json:"model"`
+	SystemFingerprint string    `json:"system_fingerprint"`
+	Choices           []Choices `json:"choices"`
+	Usage             Usage     `json:"usage"`
 }
 
 func (o OpenAIResponse) ToEventString() string {
@@ -251,6 +200,24 @@ func (o OpenAIResponse) ToEventString() string {
 	return "data: " + string(bytesRsp)
 }
 
+type StreamError struct {
+	Message string `json:"message"`
+	Type    string `json:"type"`
+	Code    string `json:"code"`
+}
+
+// streamErrorEvent reports a failure in the middle of a stream, once the
+// status code has already gone out.
+func streamErrorEvent(message, code string) string {
+	bytesRsp, err := json.Marshal(map[string]StreamError{
+		"error": {Message: message, Type: "server_error", Code: code},
+	})
+	if err != nil {
+		panic(err)
+	}
+	return "data: " + string(bytesRsp)
+}
+
 type ChatMessagePart struct {
 	Type      string `json:"type,omitempty"`
 	Text      string `json:"text,omitempty"`
@@ -324,11 +291,12 @@ type Usage struct {
 }
 
 type OpenAIStreamResponse struct {
-	ID      string          `json:"id"`
-	Object  string          `json:"object"`
-	Created int             `json:"created"`
-	Model   string          `json:"model"`
-	Choices []StreamChoices `json:"choices"`
+	ID                string          `json:"id"`
+	Object            string          `json:"object"`
+	Created           int             `json:"created"`
+	Model             string          `json:"model"`
+	SystemFingerprint string          `json:"system_fingerprint"`
+	Choices           []StreamChoices `json:"choices"`
 }
 
 func (o OpenAIStreamResponse) ToEventString() string {
@@ -351,47 +319,6 @@ type StreamChoices struct {
 	FinishReason *string `json:"finish_reason"`
 }
 
-type GetAIInfoResponse struct {
-	Models        []ModelInfo `json:"models"`
-	DefaultModels struct {
-		Chat        string `json:"chat"`
-		QuickAi     string `json:"quick_ai"`
-		Commands    string `json:"commands"`
-		API         string `json:"api"`
-		EmojiSearch string `json:"emoji_search"`
-	} `json:"default_models"`
-}
-
-func (m GetAIInfoResponse) SupporedModels() map[string]string {
-	models := map[string]string{}
-	for _, model := range m.Models {
-		models[model.Model] = model.Provider
-	}
-	return models
-}
-
-type ModelInfo struct {
-	ID                     string   `json:"id"`
-	Name                   string   `json:"name"`
-	Description            string   `json:"description"`
-	Status                 any      `json:"status"`
-	Features               []string `json:"features"`
-	Suggestions            []any    `json:"suggestions"`
-	InBetterAiSubscription bool     `json:"in_better_ai_subscription"`
-	Model                  string   `json:"model"`
-	Provider               string   `json:"provider"`
-	ProviderName           string   `json:"provider_name"`
-	ProviderBrand          string   `json:"provider_brand"`
-	Speed                  int      `json:"speed"`
-	Intelligence           float64  `json:"intelligence"`
-	RequiresBetterAi       bool     `json:"requires_better_ai"`
-	Context                int      `json:"context"`
-	Capabilities           struct {
-		WebSearch       string `json:"web_search,omitempty"`
-		ImageGeneration string `json:"image_generation,omitempty"`
-	} `json:"capabilities,omitempty"`
-}
-
 func BuildOpenAIStrMessage(origin interface{}) (UnTypedOpenAIMessage, error) {
 	raw, err := json.Marshal(origin)
 	if err != nil {
@@ -0,0 +1,48 @@
+package chat
+
+import "testing"
+
+func TestToRayChatRequestSystem(t *testing.T) {
+	tests := []struct {
+		name     string
+		messages []interface{}
+		system   string
+		sent     int
+	}{
+		{
+			name:     "no system message",
+			messages: []interface{}{map[string]string{"role": "user", "content": "hi"}},
+			sent:     1,
+		},
+		{
+			name: "system message",
+			messages: []interface{}{
+				map[string]string{"role": "system", "content": "be brief"},
+				map[string]string{"role": "user", "content": "hi"},
+			},
+			system: "be brief",
+			sent:   1,
+		},
+		{
+			name: "parted system message",
+			messages: []interface{}{
+				map[string]interface{}{"role": "system", "content": []map[string]string{{"type": "text", "text": "be brief"}}},
+				map[string]string{"role": "user", "content": "hi"},
+			},
+			system: "be brief",
+			sent:   1,
+		},
+	}
+	cat := Catalog{Models: map[string]string{"test-model": "test"}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := OpenAIRequest{Model: "test-model", Messages: tt.messages}.ToRayChatRequest(cat)
+			if req.AdditionalSystemInstructions != tt.system {
+				t.Errorf("system = %q, want %q", req.AdditionalSystemInstructions, tt.system)
+			}
+			if len(req.Messages) != tt.sent {
+				t.Errorf("sent %d messages, want %d", len(req.Messages), tt.sent)
+			}
+		})
+	}
+}
@@ -0,0 +1,90 @@
+package chat
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/Ken288yzs1/raychat/metrics"
+	"github.com/Ken288yzs1/raychat/settings"
+)
+
+// RaycastUpstream is the name of the built-in upstream, everything not
+// routed elsewhere goes there.
+const RaycastUpstream = "raycast"
+
+// Upstream is a backend completions are sent to. Whatever the backend
+// speaks, a successful response body is a raycast style event stream so
+// the endpoints can treat all of them alike.
+type Upstream interface {
+	Name() string
+	Models(ctx context.Context) ([]ModelInfo, error)
+	Chat(ctx context.Context, req RayChatRequest) (*http.Response, error)
+}
+
+// raycastUpstream sends requests with the service's current session.
+type raycastUpstream struct {
+	service *Service
+}
+
+func (u raycastUpstream) Name() string {
+	return RaycastUpstream
+}
+
+func (u raycastUpstream) Models(ctx context.Context) ([]ModelInfo, error) {
+	info, err := Cli(u.service.getToken()).GetModelInfo(ctx)
+	return info.Models, err
+}
+
+func (u raycastUpstream) Chat(ctx context.Context, req RayChatRequest) (*http.Response, error) {
+	return Cli(u.service.getToken()).Chat(ctx, req)
+}
+
+// newUpstreams builds raycast and the providers from the config.
+func newUpstreams(s *Service, providers []settings.Provider) map[string]Upstream {
+	upstreams := map[string]Upstream{RaycastUpstream: raycastUpstream{service: s}}
+	for _, p := range providers {
+		upstreams[p.Name] = NewOpenAIUpstream(p)
+	}
+	return upstreams
+}
+
+// Upstream picks the upstream for model: a MODEL_ROUTES entry, exact names
+// before the longest matching pattern, then the upstream listing the model,
+// then raycast.
+func (c Catalog) Upstream(model string) string {
+	routes := settings.Get().ModelRoutes
+	if name, ok := routes[model]; ok {
+		return name
+	}
+	best, bestLen := "", -1
+	for pattern, name := range routes {
+		prefix, ok := strings.CutSuffix(pattern, "*")
+		if ok && strings.HasPrefix(model, prefix) && len(prefix) > bestLen {
+			best, bestLen = name, len(prefix)
+		}
+	}
+	if best != "" {
+		return best
+	}
+	if name, ok := c.Upstreams[model]; ok {
+		return name
+	}
+	return RaycastUpstream
+}
+
+// ModelLabel is the metrics label of model, models outside the catalog,
+// e.g. ones only matched by a MODEL_ROUTES pattern, share one label.
+func (c Catalog) ModelLabel(model string) string {
+	if _, ok := c.Models[model]; ok {
+		return model
+	}
+	return metrics.OtherModel
+}
+
+func (s *Service) upstream(name string) Upstream {
+	if u, ok := s.upstreams[name]; ok {
+		return u
+	}
+	return s.upstreams[RaycastUpstream]
+}
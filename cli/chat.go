@@ -0,0 +1,112 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+
+	"github.com/Ken288yzs1/raychat/chat"
+	"github.com/Ken288yzs1/raychat/settings"
+)
+
+func chatOnce(args []string) error {
+	fs := newFlagSet("chat")
+	model := fs.String("model", "", "model to use, the account's default chat model when empty")
+	system := fs.String("system", "", "system prompt")
+	temperature := fs.Float64("temperature", 1, "sampling temperature")
+	reasoning := fs.Bool("reasoning", false, "print the model's reasoning to stderr")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	conf, err := loadSettings(fs)
+	if err != nil {
+		return err
+	}
+
+	prompt := strings.Join(fs.Args(), " ")
+	if prompt == "" {
+		raw, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return err
+		}
+		prompt = string(raw)
+	}
+	if strings.TrimSpace(prompt) == "" {
+		return errors.New("empty prompt, pass it as arguments or on stdin")
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	cli, err := client(conf)
+	if err != nil {
+		return err
+	}
+	info, err := cli.GetModelInfo(ctx)
+	if err != nil {
+		return err
+	}
+	name, provider, err := resolveModel(info, *model)
+	if err != nil {
+		return err
+	}
+
+	req := chat.NewRayChatRequest(name, provider, []chat.RayChatMessage{
+		{Author: "user", Content: chat.Content{Text: prompt}},
+	}, *system)
+	req.Temperature = *temperature
+	_, err = stream(ctx, cli, req, func(resp chat.RayChatStreamResponse) {
+		if *reasoning && resp.Reasoning != "" {
+			fmt.Fprint(os.Stderr, resp.Reasoning)
+		}
+		fmt.Print(resp.Text)
+	})
+	fmt.Println()
+	return err
+}
+
+// resolveModel maps a model name or alias to the catalog, empty picks the
+// account's default.
+func resolveModel(info chat.GetAIInfoResponse, model string) (string, string, error) {
+	if model == "" {
+		if model = info.DefaultModels.Chat; model == "" {
+			return "", "", errors.New("the account has no default model, pick one with -model")
+		}
+	}
+	if target, ok := settings.Get().ModelAliases[model]; ok {
+		model = target
+	}
+	provider, ok := info.SupportedModels()[model]
+	if !ok {
+		return "", "", fmt.Errorf("unknown model %q, see `raychat models`", model)
+	}
+	return model, provider, nil
+}
+
+// stream sends req and hands every event to fn as it arrives, it returns the
+// whole completion.
+func stream(ctx context.Context, cli *chat.RayChat, req chat.RayChatRequest, fn func(chat.RayChatStreamResponse)) (chat.RayChatStreamResponses, error) {
+	res, err := cli.Chat(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(res.Body, 4096))
+		return nil, fmt.Errorf("raycast returned %s: %s", res.Status, strings.TrimSpace(string(body)))
+	}
+	var resps chat.RayChatStreamResponses
+	err = chat.ReadStream(res.Body, func(resp chat.RayChatStreamResponse) error {
+		resps = append(resps, resp)
+		fn(resp)
+		return nil
+	})
+	if ctx.Err() != nil {
+		return resps, ctx.Err()
+	}
+	return resps, err
+}
@@ -0,0 +1,124 @@
+package cli
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/Ken288yzs1/raychat/chat"
+	"github.com/Ken288yzs1/raychat/logging"
+	"github.com/Ken288yzs1/raychat/settings"
+
+	"github.com/sirupsen/logrus"
+)
+
+type command struct {
+	name    string
+	summary string
+	run     func(args []string) error
+}
+
+var commands = []command{
+	{"serve", "start the openai compatible api server (default)", serve},
+	{"login", "log into raycast and print or save the token", login},
+	{"models", "list the models of the raycast account", models},
+	{"chat", "send one prompt, from the arguments or stdin, and stream the answer", chatOnce},
+	{"repl", "chat interactively, keeping the conversation", replCmd},
+	{"encrypt-credentials", "encrypt the credentials yaml on stdin with CREDENTIALS_KEY", encryptCredentials},
+}
+
+// Run dispatches to the subcommand named by args[0], no arguments serve.
+// It returns the process exit code.
+func Run(args []string) int {
+	name, rest := "serve", args
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		name, rest = args[0], args[1:]
+	}
+	if name == "help" {
+		usage(os.Stdout)
+		return 0
+	}
+	for _, cmd := range commands {
+		if cmd.name != name {
+			continue
+		}
+		err := cmd.run(rest)
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "raychat %s: %v\n", name, err)
+			return 1
+		}
+		return 0
+	}
+	fmt.Fprintf(os.Stderr, "raychat: unknown command %q\n\n", name)
+	usage(os.Stderr)
+	return 2
+}
+
+func usage(w io.Writer) {
+	fmt.Fprintln(w, "usage: raychat <command> [flags]")
+	fmt.Fprintln(w)
+	for _, cmd := range commands {
+		fmt.Fprintf(w, "  %-20s %s\n", cmd.name, cmd.summary)
+	}
+	fmt.Fprintln(w, "\nrun raychat <command> -h for the flags of a command")
+}
+
+func newFlagSet(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet("raychat "+name, flag.ContinueOnError)
+	fs.String("config", "", "yaml or toml config file, same as CONFIG_FILE")
+	return fs
+}
+
+// loadSettings reads the config like the server does. Outside the server
+// only warnings are worth printing.
+func loadSettings(fs *flag.FlagSet) (settings.RayConfig, error) {
+	if path := fs.Lookup("config").Value.String(); path != "" {
+		os.Setenv("CONFIG_FILE", path)
+	}
+	logrus.SetLevel(logrus.ErrorLevel)
+	conf, err := settings.Load()
+	if err != nil {
+		return conf, err
+	}
+	settings.Set(conf)
+	logging.Setup(conf)
+	if !conf.Debug {
+		logrus.SetLevel(logrus.WarnLevel)
+	}
+	return conf, nil
+}
+
+// tokenFile is where `raychat login -save` keeps the token.
+func tokenFile() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "raychat", "token"), nil
+}
+
+// client uses TOKEN when set and the token saved by `raychat login` otherwise.
+func client(conf settings.RayConfig) (*chat.RayChat, error) {
+	if conf.Token != "" || conf.UpstreamMode == chat.UpstreamReplay {
+		return chat.Cli(conf.Token), nil
+	}
+	path, err := tokenFile()
+	if err != nil {
+		return nil, err
+	}
+	token, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, errors.New("no token, set TOKEN or run `raychat login -save`")
+	}
+	if err != nil {
+		return nil, err
+	}
+	return chat.Cli(strings.TrimSpace(string(token))), nil
+}
@@ -0,0 +1,100 @@
+package cli
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/Ken288yzs1/raychat/auth"
+	"github.com/Ken288yzs1/raychat/transport"
+
+	"golang.org/x/term"
+)
+
+func login(args []string) error {
+	fs := newFlagSet("login")
+	email := fs.String("email", "", "raycast account email, prompted for when empty and EMAIL is not set")
+	save := fs.Bool("save", false, "save the token for `raychat models` and `raychat chat` instead of printing it")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	conf, err := loadSettings(fs)
+	if err != nil {
+		return err
+	}
+	if conf.ClientID == "" || conf.ClientSecret == "" {
+		return errors.New("CLIENT_ID and CLIENT_SECRET are required to log in")
+	}
+
+	in := bufio.NewReader(os.Stdin)
+	if *email == "" {
+		*email = conf.Email
+	}
+	if *email == "" {
+		if *email, err = prompt(in, "email: "); err != nil {
+			return err
+		}
+	}
+	password := conf.Password
+	if password == "" {
+		if password, err = promptPassword(in, "password: "); err != nil {
+			return err
+		}
+	}
+
+	a := &auth.RaycastAuth{
+		ClientID:     conf.ClientID,
+		ClientSecret: conf.ClientSecret,
+		Email:        *email,
+		Password:     password,
+		Proxy:        transport.Proxy,
+	}
+	token, err := a.Login()
+	if err != nil {
+		return err
+	}
+	if !*save {
+		fmt.Println(token)
+		return nil
+	}
+	path, err := tokenFile()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, []byte(token+"\n"), 0o600); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "logged in as %s, token saved to %s\n", a.LoginResp.User.Email, path)
+	return nil
+}
+
+func prompt(in *bufio.Reader, label string) (string, error) {
+	fmt.Fprint(os.Stderr, label)
+	line, err := in.ReadString('\n')
+	if err != nil && line == "" {
+		return "", err
+	}
+	return strings.TrimSpace(line), nil
+}
+
+// promptPassword doesn't echo when stdin is a terminal and reads a plain
+// line when it is piped.
+func promptPassword(in *bufio.Reader, label string) (string, error) {
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return prompt(in, label)
+	}
+	fmt.Fprint(os.Stderr, label)
+	password, err := term.ReadPassword(fd)
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return "", err
+	}
+	return string(password), nil
+}
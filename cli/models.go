@@ -0,0 +1,75 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/Ken288yzs1/raychat/chat"
+)
+
+func models(args []string) error {
+	fs := newFlagSet("models")
+	asJSON := fs.Bool("json", false, "print the raw model info as json")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	conf, err := loadSettings(fs)
+	if err != nil {
+		return err
+	}
+	cli, err := client(conf)
+	if err != nil {
+		return err
+	}
+	info, err := cli.GetModelInfo(context.Background())
+	if err != nil {
+		return err
+	}
+	sort.Slice(info.Models, func(i, j int) bool {
+		a, b := info.Models[i], info.Models[j]
+		if a.Provider != b.Provider {
+			return a.Provider < b.Provider
+		}
+		return a.Model < b.Model
+	})
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(info.Models)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "MODEL\tPROVIDER\tCONTEXT\tSPEED\tINTELLIGENCE\tCAPABILITIES")
+	for _, m := range info.Models {
+		model := m.Model
+		if model == info.DefaultModels.Chat {
+			model += " (default)"
+		}
+		provider := m.ProviderName
+		if provider == "" {
+			provider = m.Provider
+		}
+		fmt.Fprintf(w, "%s\t%s\t%d\t%d\t%g\t%s\n",
+			model, provider, m.Context, m.Speed, m.Intelligence, strings.Join(capabilities(m), ","))
+	}
+	return w.Flush()
+}
+
+func capabilities(m chat.ModelInfo) []string {
+	caps := append([]string{}, m.Features...)
+	if m.Capabilities.WebSearch != "" {
+		caps = append(caps, "web_search")
+	}
+	if m.Capabilities.ImageGeneration != "" {
+		caps = append(caps, "image_generation")
+	}
+	if m.RequiresBetterAi {
+		caps = append(caps, "advanced_ai")
+	}
+	return caps
+}
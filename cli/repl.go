@@ -0,0 +1,242 @@
+package cli
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+
+	"github.com/Ken288yzs1/raychat/chat"
+
+	"golang.org/x/term"
+)
+
+const replHelp = `type a message and press enter, end a line with \ to continue it
+  /model [name]   show or switch the model, lists the models without a name
+  /system [text]  set the system prompt, clears it without text
+  /reasoning      toggle showing the model's reasoning
+  /save <file>    save the session, .md as markdown, anything else as json
+  /load <file>    load a session saved with /save
+  /clear          forget the conversation, keeps model and system prompt
+  /exit           quit, so does ctrl-d`
+
+type repl struct {
+	cli       *chat.RayChat
+	info      chat.GetAIInfoResponse
+	session   session
+	reasoning bool
+	color     bool
+	in        *bufio.Reader
+}
+
+func replCmd(args []string) error {
+	fs := newFlagSet("repl")
+	model := fs.String("model", "", "model to start with, the account's default chat model when empty")
+	system := fs.String("system", "", "system prompt")
+	load := fs.String("load", "", "session file to continue")
+	reasoning := fs.Bool("reasoning", false, "show the model's reasoning")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	conf, err := loadSettings(fs)
+	if err != nil {
+		return err
+	}
+	cli, err := client(conf)
+	if err != nil {
+		return err
+	}
+	info, err := cli.GetModelInfo(context.Background())
+	if err != nil {
+		return err
+	}
+
+	r := &repl{
+		cli:       cli,
+		info:      info,
+		reasoning: *reasoning,
+		color:     term.IsTerminal(int(os.Stdout.Fd())),
+		in:        bufio.NewReader(os.Stdin),
+	}
+	if *load != "" {
+		if r.session, err = loadSession(*load); err != nil {
+			return err
+		}
+	}
+	if *model != "" || r.session.Model == "" {
+		if r.session.Model, _, err = resolveModel(info, *model); err != nil {
+			return err
+		}
+	}
+	if *system != "" {
+		r.session.System = *system
+	}
+	fmt.Printf("raychat with %s, /help for commands\n", r.session.Model)
+	return r.run()
+}
+
+func (r *repl) run() error {
+	for {
+		line, err := r.readInput()
+		if errors.Is(err, io.EOF) {
+			fmt.Println()
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		line = strings.TrimSpace(line)
+		switch {
+		case line == "":
+		case strings.HasPrefix(line, "/"):
+			if quit := r.command(line); quit {
+				return nil
+			}
+		default:
+			r.send(line)
+		}
+	}
+}
+
+// readInput reads one message, lines ending with a backslash continue it.
+func (r *repl) readInput() (string, error) {
+	var lines []string
+	fmt.Print("> ")
+	for {
+		line, err := r.in.ReadString('\n')
+		if err != nil && (line == "" || !errors.Is(err, io.EOF)) {
+			return "", err
+		}
+		line = strings.TrimRight(line, "\r\n")
+		if !strings.HasSuffix(line, "\\") {
+			return strings.Join(append(lines, line), "\n"), nil
+		}
+		lines = append(lines, strings.TrimSuffix(line, "\\"))
+		fmt.Print(". ")
+	}
+}
+
+func (r *repl) command(line string) (quit bool) {
+	name, arg, _ := strings.Cut(line, " ")
+	arg = strings.TrimSpace(arg)
+	switch name {
+	case "/help":
+		fmt.Println(replHelp)
+	case "/exit", "/quit":
+		return true
+	case "/model":
+		if arg == "" {
+			fmt.Printf("using %s, available:\n", r.session.Model)
+			for _, m := range r.info.Models {
+				fmt.Printf("  %s\n", m.Model)
+			}
+			return false
+		}
+		model, _, err := resolveModel(r.info, arg)
+		if err != nil {
+			fmt.Println(err)
+			return false
+		}
+		r.session.Model = model
+		fmt.Printf("switched to %s\n", model)
+	case "/system":
+		r.session.System = arg
+		if arg == "" {
+			fmt.Println("system prompt cleared")
+		} else {
+			fmt.Println("system prompt set")
+		}
+	case "/reasoning":
+		r.reasoning = !r.reasoning
+		fmt.Printf("reasoning display %s\n", map[bool]string{true: "on", false: "off"}[r.reasoning])
+	case "/save":
+		if arg == "" {
+			fmt.Println("usage: /save <file>")
+			return false
+		}
+		if err := r.session.save(arg); err != nil {
+			fmt.Println(err)
+			return false
+		}
+		fmt.Printf("saved %d messages to %s\n", len(r.session.Messages), arg)
+	case "/load":
+		if arg == "" {
+			fmt.Println("usage: /load <file>")
+			return false
+		}
+		s, err := loadSession(arg)
+		if err != nil {
+			fmt.Println(err)
+			return false
+		}
+		if s.Model == "" {
+			s.Model = r.session.Model
+		}
+		r.session = s
+		fmt.Printf("loaded %d messages, using %s\n", len(s.Messages), s.Model)
+	case "/clear":
+		r.session.Messages = nil
+		fmt.Println("conversation cleared")
+	default:
+		fmt.Printf("unknown command %s, /help lists them\n", name)
+	}
+	return false
+}
+
+// send streams the answer to text, ctrl-c stops the answer but not the repl.
+func (r *repl) send(text string) {
+	_, provider, err := resolveModel(r.info, r.session.Model)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	r.session.Messages = append(r.session.Messages, turn{Role: "user", Content: text})
+	req := chat.NewRayChatRequest(r.session.Model, provider, r.session.rayMessages(), r.session.System)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	inReasoning := false
+	resps, err := stream(ctx, r.cli, req, func(resp chat.RayChatStreamResponse) {
+		if r.reasoning && resp.Reasoning != "" {
+			if !inReasoning && r.color {
+				fmt.Print("\033[2m")
+			}
+			inReasoning = true
+			fmt.Print(resp.Reasoning)
+		}
+		if resp.Text != "" {
+			if inReasoning {
+				r.endReasoning()
+				inReasoning = false
+			}
+			fmt.Print(resp.Text)
+		}
+	})
+	if inReasoning {
+		r.endReasoning()
+	}
+	fmt.Println()
+
+	content, reasoning := resps.Join()
+	if err != nil && content == "" {
+		// nothing came back, drop the question so it can be asked again
+		r.session.Messages = r.session.Messages[:len(r.session.Messages)-1]
+		fmt.Println("error:", err)
+		return
+	}
+	if err != nil {
+		fmt.Println("answer cut short:", err)
+	}
+	r.session.Messages = append(r.session.Messages, turn{Role: "assistant", Content: content, Reasoning: reasoning})
+}
+
+func (r *repl) endReasoning() {
+	if r.color {
+		fmt.Print("\033[0m")
+	}
+	fmt.Print("\n\n")
+}
@@ -0,0 +1,69 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/Ken288yzs1/raychat/service"
+	"github.com/Ken288yzs1/raychat/settings"
+)
+
+func serve(args []string) error {
+	fs := newFlagSet("serve")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if path := fs.Lookup("config").Value.String(); path != "" {
+		os.Setenv("CONFIG_FILE", path)
+	}
+	conf, err := settings.Load()
+	if err != nil {
+		return fmt.Errorf("load settings: %w", err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	srv, err := service.New(conf)
+	if err != nil {
+		return fmt.Errorf("start server: %w", err)
+	}
+	if err := srv.Start(ctx); err != nil {
+		return fmt.Errorf("start server: %w", err)
+	}
+
+	var serveErr error
+	select {
+	case <-ctx.Done():
+	case serveErr = <-srv.Err():
+	}
+	stop()
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), conf.ShutdownTimeout)
+	defer cancel()
+	return errors.Join(serveErr, srv.Shutdown(shutdownCtx))
+}
+
+// encryptCredentials seals the credentials yaml on stdin with CREDENTIALS_KEY
+// for use as CREDENTIALS_FILE.
+func encryptCredentials(args []string) error {
+	fs := newFlagSet("encrypt-credentials")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	plain, err := io.ReadAll(os.Stdin)
+	if err != nil {
+		return err
+	}
+	sealed, err := settings.EncryptCredentials(plain, os.Getenv("CREDENTIALS_KEY"))
+	if err != nil {
+		return fmt.Errorf("%w, create a key with `openssl rand -base64 32`", err)
+	}
+	_, err = os.Stdout.Write(sealed)
+	return err
+}
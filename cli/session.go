@@ -0,0 +1,139 @@
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/Ken288yzs1/raychat/chat"
+)
+
+// session is a REPL conversation, saved as json or markdown.
+type session struct {
+	Model    string `json:"model"`
+	System   string `json:"system,omitempty"`
+	Messages []turn `json:"messages"`
+}
+
+type turn struct {
+	Role      string `json:"role"`
+	Content   string `json:"content"`
+	Reasoning string `json:"reasoning,omitempty"`
+}
+
+func (s *session) rayMessages() []chat.RayChatMessage {
+	msgs := make([]chat.RayChatMessage, 0, len(s.Messages))
+	for _, t := range s.Messages {
+		msgs = append(msgs, chat.RayChatMessage{Author: t.Role, Content: chat.Content{Text: t.Content}})
+	}
+	return msgs
+}
+
+// save picks the format from the extension, .md is markdown and anything
+// else json.
+func (s *session) save(path string) error {
+	var data []byte
+	if isMarkdown(path) {
+		data = []byte(s.markdown())
+	} else {
+		var err error
+		if data, err = json.MarshalIndent(s, "", "  "); err != nil {
+			return err
+		}
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+func loadSession(path string) (session, error) {
+	var s session
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return s, err
+	}
+	if isMarkdown(path) {
+		return parseMarkdown(string(data))
+	}
+	if err := json.Unmarshal(data, &s); err != nil {
+		return s, fmt.Errorf("parse %s: %w", path, err)
+	}
+	return s, nil
+}
+
+func isMarkdown(path string) bool {
+	ext := strings.ToLower(filepath.Ext(path))
+	return ext == ".md" || ext == ".markdown"
+}
+
+// in markdown every message is a level two heading, preceded by a comment
+// marker so that headings inside answers don't confuse loading it back
+const markerPrefix = "<!-- raychat:"
+
+func marker(kind string) string {
+	return markerPrefix + kind + " -->\n"
+}
+
+func (s *session) markdown() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# raychat session\n\nmodel: `%s`\n\n", s.Model)
+	if s.System != "" {
+		fmt.Fprintf(&b, "%s## system\n\n%s\n\n", marker("system"), s.System)
+	}
+	for _, t := range s.Messages {
+		fmt.Fprintf(&b, "%s## %s\n\n", marker(t.Role), t.Role)
+		if t.Reasoning != "" {
+			fmt.Fprintf(&b, "%s%s\n\n%s", marker("reasoning"), quote(t.Reasoning), marker("answer"))
+		}
+		fmt.Fprintf(&b, "%s\n\n", t.Content)
+	}
+	return b.String()
+}
+
+func parseMarkdown(doc string) (session, error) {
+	var s session
+	chunks := strings.Split(doc, markerPrefix)
+	if _, model, ok := strings.Cut(chunks[0], "model: `"); ok {
+		s.Model, _, _ = strings.Cut(model, "`")
+	}
+	var reasoning string
+	for _, chunk := range chunks[1:] {
+		kind, body, ok := strings.Cut(chunk, " -->")
+		if !ok {
+			return s, errors.New("unterminated raychat marker")
+		}
+		body = strings.TrimSpace(body)
+		// drop the heading repeating the role
+		body = strings.TrimSpace(strings.TrimPrefix(body, "## "+kind))
+		switch kind {
+		case "system":
+			s.System = body
+		case "user", "assistant":
+			s.Messages = append(s.Messages, turn{Role: kind, Content: body})
+		case "reasoning":
+			reasoning = unquote(body)
+		case "answer":
+			if len(s.Messages) == 0 {
+				return s, errors.New("answer without a message")
+			}
+			last := &s.Messages[len(s.Messages)-1]
+			last.Content, last.Reasoning = body, reasoning
+		default:
+			return s, fmt.Errorf("unknown section %q", kind)
+		}
+	}
+	return s, nil
+}
+
+func quote(text string) string {
+	return "> " + strings.ReplaceAll(text, "\n", "\n> ")
+}
+
+func unquote(text string) string {
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimPrefix(strings.TrimPrefix(line, ">"), " ")
+	}
+	return strings.Join(lines, "\n")
+}
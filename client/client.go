@@ -0,0 +1,256 @@
+// Package client talks to raycast AI directly, for Go programs that would
+// rather embed raychat than run the server next to them. It keeps no global
+// state, logs nothing and reports every failure as an error.
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Ken288yzs1/raychat/auth"
+	"github.com/Ken288yzs1/raychat/raycast"
+
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	// ErrNoCredentials is returned by New without a token or a full set of
+	// login credentials.
+	ErrNoCredentials = errors.New("raychat: a token or client id, client secret, email and password are required")
+	// ErrUnknownModel is returned by Chat for models the account doesn't have.
+	ErrUnknownModel = errors.New("raychat: unknown model")
+)
+
+// APIError is a non 200 answer from raycast.
+type APIError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("raychat: raycast returned %d %s: %s", e.StatusCode, http.StatusText(e.StatusCode), e.Body)
+}
+
+// apiError reads a failed answer and closes it.
+func apiError(res *http.Response) *APIError {
+	defer res.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(res.Body, 4096))
+	return &APIError{StatusCode: res.StatusCode, Body: strings.TrimSpace(string(body))}
+}
+
+type Config struct {
+	// Token is a raycast access token, e.g. from `raychat login`. Without it
+	// New logs in with the credentials below.
+	Token        string
+	ClientID     string
+	ClientSecret string
+	Email        string
+	Password     string
+	// HTTPClient sends models and completion requests. When nil the client
+	// gets one with connect and response header timeouts but no overall
+	// timeout, which would cut long completions. Logging in always uses its
+	// own client.
+	HTTPClient *http.Client
+}
+
+// Client is safe for concurrent use.
+type Client struct {
+	token string
+	http  *http.Client
+
+	mu        sync.Mutex
+	providers map[string]string
+}
+
+// New returns a client for cfg, logging into raycast first when no token is
+// given.
+func New(cfg Config) (*Client, error) {
+	httpClient := cfg.HTTPClient
+	if httpClient == nil {
+		httpClient = defaultHTTPClient()
+	}
+	token := cfg.Token
+	if token == "" {
+		if cfg.ClientID == "" || cfg.ClientSecret == "" || cfg.Email == "" || cfg.Password == "" {
+			return nil, ErrNoCredentials
+		}
+		a := &auth.RaycastAuth{
+			ClientID:     cfg.ClientID,
+			ClientSecret: cfg.ClientSecret,
+			Email:        cfg.Email,
+			Password:     cfg.Password,
+			Log:          quietLogger(),
+		}
+		var err error
+		if token, err = a.Login(); err != nil {
+			return nil, fmt.Errorf("raychat: login: %w", err)
+		}
+	}
+	return &Client{token: token, http: httpClient}, nil
+}
+
+func defaultHTTPClient() *http.Client {
+	dialer := &net.Dialer{Timeout: 10 * time.Second, KeepAlive: 30 * time.Second}
+	return &http.Client{Transport: &http.Transport{
+		Proxy:                 http.ProxyFromEnvironment,
+		DialContext:           dialer.DialContext,
+		ForceAttemptHTTP2:     true,
+		MaxIdleConns:          10,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ResponseHeaderTimeout: 2 * time.Minute,
+		ExpectContinueTimeout: time.Second,
+	}}
+}
+
+// Token returns the access token in use, worth keeping after logging in
+// with credentials.
+func (c *Client) Token() string {
+	return c.token
+}
+
+func quietLogger() *logrus.Entry {
+	l := logrus.New()
+	l.SetOutput(io.Discard)
+	return logrus.NewEntry(l)
+}
+
+type Model struct {
+	// ID is what Request.Model takes
+	ID           string
+	Name         string
+	Description  string
+	Provider     string
+	ProviderName string
+	// Context is the context window in thousands of tokens
+	Context      int
+	Speed        int
+	Intelligence float64
+	Features     []string
+	// RequiresAdvancedAI is set for models that need the advanced ai add-on
+	RequiresAdvancedAI bool
+}
+
+// Models lists the models of the account.
+func (c *Client) Models(ctx context.Context) ([]Model, error) {
+	req, err := raycast.NewModelsRequest(ctx, c.token)
+	if err != nil {
+		return nil, fmt.Errorf("raychat: %w", err)
+	}
+	res, err := c.http.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("raychat: get model info failed: %w", err)
+	}
+	if res.StatusCode != http.StatusOK {
+		return nil, apiError(res)
+	}
+	info, err := raycast.DecodeModels(res)
+	if err != nil {
+		return nil, fmt.Errorf("raychat: %w", err)
+	}
+	models := make([]Model, 0, len(info.Models))
+	for _, m := range info.Models {
+		models = append(models, Model{
+			ID:                 m.Model,
+			Name:               m.Name,
+			Description:        m.Description,
+			Provider:           m.Provider,
+			ProviderName:       m.ProviderName,
+			Context:            m.Context,
+			Speed:              m.Speed,
+			Intelligence:       m.Intelligence,
+			Features:           m.Features,
+			RequiresAdvancedAI: m.RequiresBetterAi,
+		})
+	}
+	c.mu.Lock()
+	c.providers = info.SupportedModels()
+	c.mu.Unlock()
+	return models, nil
+}
+
+// provider looks the model up in the catalog, loading it again when the
+// model is missing in case it is new.
+func (c *Client) provider(ctx context.Context, model string) (string, error) {
+	if provider, ok := c.cachedProvider(model); ok {
+		return provider, nil
+	}
+	if _, err := c.Models(ctx); err != nil {
+		return "", err
+	}
+	if provider, ok := c.cachedProvider(model); ok {
+		return provider, nil
+	}
+	return "", fmt.Errorf("%w %q", ErrUnknownModel, model)
+}
+
+func (c *Client) cachedProvider(model string) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	provider, ok := c.providers[model]
+	return provider, ok
+}
+
+type Message struct {
+	// Role is user or assistant, system messages are sent as user messages
+	// like the raycast app does, prefer Request.System for instructions
+	Role    string
+	Content string
+}
+
+type Request struct {
+	Model string
+	// Provider of the model, looked up from the catalog when empty
+	Provider string
+	System   string
+	Messages []Message
+	// Temperature defaults to 1
+	Temperature float64
+}
+
+// Chat starts a completion. The stream must be closed, reading it to the
+// end is not enough when the caller stops early.
+func (c *Client) Chat(ctx context.Context, req Request) (*Stream, error) {
+	if len(req.Messages) == 0 {
+		return nil, errors.New("raychat: no messages")
+	}
+	provider := req.Provider
+	if provider == "" {
+		var err error
+		if provider, err = c.provider(ctx, req.Model); err != nil {
+			return nil, err
+		}
+	}
+	messages := make([]raycast.Message, 0, len(req.Messages))
+	for _, m := range req.Messages {
+		role := m.Role
+		if role == "system" {
+			role = "user"
+		}
+		messages = append(messages, raycast.Message{Author: role, Content: raycast.Content{Text: m.Content}})
+	}
+	rayReq := raycast.NewChatRequest(req.Model, provider, messages, req.System)
+	if req.Temperature != 0 {
+		rayReq.Temperature = req.Temperature
+	}
+
+	httpReq, err := raycast.NewHTTPChatRequest(ctx, c.token, rayReq)
+	if err != nil {
+		return nil, fmt.Errorf("raychat: %w", err)
+	}
+	res, err := c.http.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("raychat: %w", err)
+	}
+	if res.StatusCode != http.StatusOK {
+		return nil, apiError(res)
+	}
+	return newStream(ctx, res.Body), nil
+}
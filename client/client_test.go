@@ -0,0 +1,270 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Ken288yzs1/raychat/raycast"
+)
+
+// roundTripFunc answers requests in place of raycast.
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
+
+func newTestClient(t *testing.T, fn roundTripFunc) *Client {
+	t.Helper()
+	c, err := New(Config{Token: "test-token", HTTPClient: &http.Client{Transport: fn}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func respond(status int, body string) *http.Response {
+	return &http.Response{
+		StatusCode: status,
+		Status:     http.StatusText(status),
+		Header:     http.Header{},
+		Body:       io.NopCloser(strings.NewReader(body)),
+	}
+}
+
+const modelsBody = `{"models":[
+	{"model":"openai-gpt-4o","name":"GPT-4o","provider":"openai","context":128,"speed":4,"intelligence":4.5,"features":["vision"]},
+	{"model":"anthropic-claude-opus","name":"Claude Opus","provider":"anthropic","requires_better_ai":true}
+]}`
+
+func TestNewRequiresCredentials(t *testing.T) {
+	if _, err := New(Config{Email: "a@b.c"}); !errors.Is(err, ErrNoCredentials) {
+		t.Errorf("err = %v, want ErrNoCredentials", err)
+	}
+}
+
+func TestModels(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		body    string
+		want    []string
+		wantErr *APIError
+	}{
+		{name: "ok", status: http.StatusOK, body: modelsBody, want: []string{"openai-gpt-4o", "anthropic-claude-opus"}},
+		{name: "unauthorized", status: http.StatusUnauthorized, body: " bad token\n", wantErr: &APIError{StatusCode: 401, Body: "bad token"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestClient(t, func(r *http.Request) (*http.Response, error) {
+				if r.URL.String() != raycast.ModelsURL {
+					t.Errorf("requested %s", r.URL)
+				}
+				if got := r.Header.Get("Authorization"); got != "Bearer test-token" {
+					t.Errorf("Authorization = %q", got)
+				}
+				return respond(tt.status, tt.body), nil
+			})
+			models, err := c.Models(context.Background())
+			if tt.wantErr != nil {
+				var apiErr *APIError
+				if !errors.As(err, &apiErr) || *apiErr != *tt.wantErr {
+					t.Fatalf("err = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(models) != len(tt.want) {
+				t.Fatalf("got %d models, want %d", len(models), len(tt.want))
+			}
+			for i, id := range tt.want {
+				if models[i].ID != id {
+					t.Errorf("models[%d] = %s, want %s", i, models[i].ID, id)
+				}
+			}
+			if m := models[0]; m.Provider != "openai" || m.Context != 128 || m.Intelligence != 4.5 || m.Features[0] != "vision" {
+				t.Errorf("models[0] = %+v", m)
+			}
+			if !models[1].RequiresAdvancedAI {
+				t.Error("RequiresAdvancedAI not set")
+			}
+		})
+	}
+}
+
+func TestChat(t *testing.T) {
+	tests := []struct {
+		name     string
+		req      Request
+		status   int
+		body     string
+		want     []Delta
+		wantErr  string
+		apiErr   *APIError
+		provider string
+	}{
+		{
+			name:     "stream",
+			req:      Request{Model: "openai-gpt-4o", System: "be brief"},
+			status:   http.StatusOK,
+			body:     "data: {\"reasoning\":\"hmm\"}\n\n: ping\n\ndata: {\"text\":\"hi\"}\n\ndata: {\"text\":\"\",\"finish_reason\":\"stop\"}\n\n",
+			want:     []Delta{{Reasoning: "hmm"}, {Text: "hi"}, {FinishReason: "stop"}},
+			provider: "openai",
+		},
+		{
+			name:     "given provider",
+			req:      Request{Model: "private-model", Provider: "private"},
+			status:   http.StatusOK,
+			body:     "data: {\"text\":\"hi\"}\n\n",
+			want:     []Delta{{Text: "hi"}},
+			provider: "private",
+		},
+		{
+			name:    "error event mid stream",
+			req:     Request{Model: "openai-gpt-4o"},
+			status:  http.StatusOK,
+			body:    "data: {\"text\":\"hi\"}\n\ndata: {\"error\":\"model overloaded\"}\n\ndata: {\"text\":\"lost\"}\n\n",
+			want:    []Delta{{Text: "hi"}},
+			wantErr: "model overloaded",
+		},
+		{
+			name:   "rate limited",
+			req:    Request{Model: "openai-gpt-4o"},
+			status: http.StatusTooManyRequests,
+			body:   "slow down",
+			apiErr: &APIError{StatusCode: 429, Body: "slow down"},
+		},
+		{
+			name:    "unknown model",
+			req:     Request{Model: "nope"},
+			wantErr: ErrUnknownModel.Error(),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestClient(t, func(r *http.Request) (*http.Response, error) {
+				if r.URL.String() == raycast.ModelsURL {
+					return respond(http.StatusOK, modelsBody), nil
+				}
+				var sent raycast.ChatRequest
+				if err := json.NewDecoder(r.Body).Decode(&sent); err != nil {
+					t.Fatal(err)
+				}
+				if sent.Model != tt.req.Model || sent.Provider != tt.provider && tt.provider != "" ||
+					sent.AdditionalSystemInstructions != tt.req.System || sent.Messages[0].Content.Text != "hello" {
+					t.Errorf("sent %+v", sent)
+				}
+				return respond(tt.status, tt.body), nil
+			})
+			tt.req.Messages = []Message{{Role: "user", Content: "hello"}}
+			stream, err := c.Chat(context.Background(), tt.req)
+			if tt.apiErr != nil {
+				var apiErr *APIError
+				if !errors.As(err, &apiErr) || *apiErr != *tt.apiErr {
+					t.Fatalf("err = %v, want %v", err, tt.apiErr)
+				}
+				return
+			}
+			if err != nil {
+				if tt.wantErr == "" || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			defer stream.Close()
+			var got []Delta
+			for stream.Next() {
+				got = append(got, stream.Delta())
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %+v, want %+v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("delta %d = %+v, want %+v", i, got[i], tt.want[i])
+				}
+			}
+			if tt.wantErr == "" && stream.Err() != nil {
+				t.Errorf("unexpected error: %v", stream.Err())
+			}
+			if tt.wantErr != "" && (stream.Err() == nil || !strings.Contains(stream.Err().Error(), tt.wantErr)) {
+				t.Errorf("Err() = %v, want %q", stream.Err(), tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestChatNoMessages(t *testing.T) {
+	c := newTestClient(t, func(r *http.Request) (*http.Response, error) {
+		t.Error("nothing should be sent")
+		return nil, errors.New("unexpected request")
+	})
+	if _, err := c.Chat(context.Background(), Request{Model: "openai-gpt-4o"}); err == nil {
+		t.Error("no error without messages")
+	}
+}
+
+// ctxBody blocks like a silent upstream until the request is cancelled,
+// as the real transport does.
+type ctxBody struct {
+	ctx  context.Context
+	data io.Reader
+}
+
+func (b *ctxBody) Read(p []byte) (int, error) {
+	if n, err := b.data.Read(p); n > 0 || err != io.EOF {
+		return n, err
+	}
+	<-b.ctx.Done()
+	return 0, b.ctx.Err()
+}
+
+func (b *ctxBody) Close() error {
+	return nil
+}
+
+func TestStreamCancel(t *testing.T) {
+	c := newTestClient(t, func(r *http.Request) (*http.Response, error) {
+		res := respond(http.StatusOK, "")
+		res.Body = &ctxBody{ctx: r.Context(), data: strings.NewReader("data: {\"text\":\"hi\"}\n\n")}
+		return res, nil
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	stream, err := c.Chat(ctx, Request{Model: "m", Provider: "p", Messages: []Message{{Role: "user", Content: "hello"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stream.Close()
+	if !stream.Next() || stream.Delta().Text != "hi" {
+		t.Fatalf("first delta = %+v, err %v", stream.Delta(), stream.Err())
+	}
+	time.AfterFunc(10*time.Millisecond, cancel)
+	if stream.Next() {
+		t.Fatalf("got %+v after cancel", stream.Delta())
+	}
+	if !errors.Is(stream.Err(), context.Canceled) {
+		t.Errorf("Err() = %v, want context.Canceled", stream.Err())
+	}
+}
+
+func TestChatCancelledBeforeAnswer(t *testing.T) {
+	c := newTestClient(t, func(r *http.Request) (*http.Response, error) {
+		<-r.Context().Done()
+		return nil, r.Context().Err()
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(10*time.Millisecond, cancel)
+	_, err := c.Chat(ctx, Request{Model: "m", Provider: "p", Messages: []Message{{Role: "user", Content: "hello"}}})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("err = %v, want context.Canceled", err)
+	}
+}
@@ -0,0 +1,78 @@
+package client
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/Ken288yzs1/raychat/raycast"
+)
+
+// Delta is one piece of a completion.
+type Delta struct {
+	Text      string
+	Reasoning string
+	// FinishReason is only set on the last delta, e.g. stop or length
+	FinishReason string
+}
+
+// Stream iterates over the deltas of a completion:
+//
+//	for stream.Next() {
+//		fmt.Print(stream.Delta().Text)
+//	}
+//	if err := stream.Err(); err != nil { ... }
+type Stream struct {
+	ctx     context.Context
+	body    io.ReadCloser
+	scanner *bufio.Scanner
+	delta   Delta
+	err     error
+}
+
+func newStream(ctx context.Context, body io.ReadCloser) *Stream {
+	return &Stream{ctx: ctx, body: body, scanner: bufio.NewScanner(body)}
+}
+
+// Next advances to the next delta, it returns false at the end of the
+// completion or on error.
+func (s *Stream) Next() bool {
+	if s.err != nil {
+		return false
+	}
+	for s.scanner.Scan() {
+		resp, ok, err := raycast.ParseEvent(s.scanner.Text())
+		if err != nil {
+			s.err = fmt.Errorf("raychat: %w", err)
+			return false
+		}
+		if !ok {
+			continue
+		}
+		s.delta = Delta{Text: resp.Text, Reasoning: resp.Reasoning}
+		if resp.FinishReason != nil {
+			s.delta.FinishReason = *resp.FinishReason
+		}
+		return true
+	}
+	if err := s.ctx.Err(); err != nil {
+		s.err = err
+	} else if err := s.scanner.Err(); err != nil {
+		s.err = fmt.Errorf("raychat: read stream: %w", err)
+	}
+	return false
+}
+
+func (s *Stream) Delta() Delta {
+	return s.delta
+}
+
+// Err is the reason Next stopped, nil when the completion ended normally.
+func (s *Stream) Err() error {
+	return s.err
+}
+
+func (s *Stream) Close() error {
+	return s.body.Close()
+}
@@ -0,0 +1,49 @@
+package keys
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/gin-gonic/gin"
+)
+
+const contextKey = "raychat/api_key"
+
+// FromExternalToken wraps one of the EXTERNAL_TOKEN values in an unrestricted
+// key, so everything downstream can treat both kinds of keys alike. The id
+// comes from the token itself so that reordering EXTERNAL_TOKEN keeps usage,
+// quotas and limits with the right token.
+func FromExternalToken(index int, token string) Key {
+	prefix := token
+	if len(prefix) > 4 {
+		prefix = prefix[:4]
+	}
+	return Key{
+		ID:     ExternalTokenID(token),
+		Name:   fmt.Sprintf("EXTERNAL_TOKEN #%d", index),
+		Prefix: prefix,
+	}
+}
+
+// ExternalTokenID is the key id of an EXTERNAL_TOKEN value, env_ followed by
+// the start of its sha256.
+func ExternalTokenID(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return "env_" + hex.EncodeToString(sum[:6])
+}
+
+func SetContext(c *gin.Context, k Key) {
+	c.Set(contextKey, k)
+}
+
+// FromContext returns the key the request was authenticated with, there is
+// none when auth is off.
+func FromContext(c *gin.Context) (Key, bool) {
+	v, ok := c.Get(contextKey)
+	if !ok {
+		return Key{}, false
+	}
+	k, ok := v.(Key)
+	return k, ok
+}
@@ -0,0 +1,24 @@
+package keys
+
+import "strings"
+
+// ReasoningFormat decides how the model's thinking reaches the client, most
+// clients don't know about reasoning_content and silently drop it.
+type ReasoningFormat string
+
+const (
+	// ReasoningField emits reasoning as the non-standard reasoning_content field
+	ReasoningField ReasoningFormat = "field"
+	// ReasoningThink inlines reasoning into content as a <think>...</think> block
+	ReasoningThink ReasoningFormat = "think"
+	// ReasoningHide drops reasoning entirely
+	ReasoningHide ReasoningFormat = "hide"
+)
+
+func ParseReasoningFormat(s string) (ReasoningFormat, bool) {
+	switch f := ReasoningFormat(strings.ToLower(strings.TrimSpace(s))); f {
+	case ReasoningField, ReasoningThink, ReasoningHide:
+		return f, true
+	}
+	return "", false
+}
@@ -0,0 +1,265 @@
+package keys
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	secretPrefix = "sk-raychat-"
+	secretLength = 40
+	charset      = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+)
+
+var (
+	ErrNotFound = errors.New("key not found")
+	ErrInvalid  = errors.New("invalid api key")
+	ErrDisabled = errors.New("api key is disabled")
+	ErrExpired  = errors.New("api key has expired")
+)
+
+func Logger() *logrus.Entry {
+	return logrus.WithField("prefix", "keys")
+}
+
+// Store keeps api keys in a json file, secrets are only kept as sha256
+// hashes. Every change is written through so edits survive restarts.
+type Store struct {
+	path string
+	mu   sync.RWMutex
+	keys map[string]*Key
+}
+
+// Open loads the keys stored at path, without a path they are only kept in
+// memory.
+func Open(path string) (*Store, error) {
+	s := &Store{path: path, keys: map[string]*Key{}}
+	if path == "" {
+		Logger().Info("api keys are kept in memory only, set KEYS_FILE to keep them across restarts")
+		return s, nil
+	}
+	raw, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var list []*Key
+	if err := json.Unmarshal(raw, &list); err != nil {
+		return nil, err
+	}
+	for _, k := range list {
+		s.keys[k.ID] = k
+	}
+	Logger().Infof("loaded %d api keys from %s", len(list), path)
+	return s, nil
+}
+
+func (s *Store) Len() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.keys)
+}
+
+// Lookup resolves a bearer secret to its key, rejecting disabled and expired ones.
+func (s *Store) Lookup(secret string) (Key, error) {
+	hash := hashSecret(secret)
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, k := range s.keys {
+		if k.SecretHash != hash {
+			continue
+		}
+		if k.Disabled {
+			return *k, ErrDisabled
+		}
+		if k.Expired(time.Now()) {
+			return *k, ErrExpired
+		}
+		return *k, nil
+	}
+	return Key{}, ErrInvalid
+}
+
+func (s *Store) List() []Key {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	list := make([]Key, 0, len(s.keys))
+	for _, k := range s.keys {
+		list = append(list, k.Public())
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].CreatedAt.Before(list[j].CreatedAt) })
+	return list
+}
+
+func (s *Store) Get(id string) (Key, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	k, ok := s.keys[id]
+	if !ok {
+		return Key{}, ErrNotFound
+	}
+	return k.Public(), nil
+}
+
+func (s *Store) Create(req CreateRequest) (KeyWithSecret, error) {
+	secret := secretPrefix + randomString(secretLength)
+	k := &Key{
+		ID:              "key_" + randomString(16),
+		Name:            req.Name,
+		Prefix:          secret[:len(secretPrefix)+4],
+		SecretHash:      hashSecret(secret),
+		AllowedModels:   req.AllowedModels,
+		ReasoningFormat: req.ReasoningFormat,
+		ExpiresAt:       req.ExpiresAt,
+		Metadata:        req.Metadata,
+		RateLimit:       req.RateLimit,
+		Quotas:          req.Quotas,
+		NoTranscripts:   req.NoTranscripts,
+		CreatedAt:       time.Now(),
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.keys[k.ID] = k
+	if err := s.save(); err != nil {
+		delete(s.keys, k.ID)
+		return KeyWithSecret{}, err
+	}
+	return KeyWithSecret{Key: k.Public(), Secret: secret}, nil
+}
+
+func (s *Store) Update(id string, req UpdateRequest) (Key, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	k, ok := s.keys[id]
+	if !ok {
+		return Key{}, ErrNotFound
+	}
+	old := *k
+	if req.Name != nil {
+		k.Name = *req.Name
+	}
+	if req.AllowedModels != nil {
+		k.AllowedModels = *req.AllowedModels
+	}
+	if req.ReasoningFormat != nil {
+		k.ReasoningFormat = *req.ReasoningFormat
+	}
+	if req.ExpiresAt != nil {
+		k.ExpiresAt = req.ExpiresAt
+	}
+	if req.ClearExpiry {
+		k.ExpiresAt = nil
+	}
+	if req.Disabled != nil {
+		k.Disabled = *req.Disabled
+	}
+	if req.Metadata != nil {
+		k.Metadata = *req.Metadata
+	}
+	if req.RateLimit != nil {
+		k.RateLimit = req.RateLimit
+	}
+	if req.Quotas != nil {
+		k.Quotas = *req.Quotas
+	}
+	if req.NoTranscripts != nil {
+		k.NoTranscripts = *req.NoTranscripts
+	}
+	if err := s.save(); err != nil {
+		*k = old
+		return Key{}, err
+	}
+	return k.Public(), nil
+}
+
+// Rotate issues a new secret for the key, the old one stops working at once.
+func (s *Store) Rotate(id string) (KeyWithSecret, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	k, ok := s.keys[id]
+	if !ok {
+		return KeyWithSecret{}, ErrNotFound
+	}
+	old := *k
+	secret := secretPrefix + randomString(secretLength)
+	now := time.Now()
+	k.Prefix = secret[:len(secretPrefix)+4]
+	k.SecretHash = hashSecret(secret)
+	k.RotatedAt = &now
+	if err := s.save(); err != nil {
+		*k = old
+		return KeyWithSecret{}, err
+	}
+	return KeyWithSecret{Key: k.Public(), Secret: secret}, nil
+}
+
+func (s *Store) Revoke(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	k, ok := s.keys[id]
+	if !ok {
+		return ErrNotFound
+	}
+	delete(s.keys, id)
+	if err := s.save(); err != nil {
+		s.keys[id] = k
+		return err
+	}
+	return nil
+}
+
+// save must be called with the write lock held, it replaces the file
+// atomically so a crash never leaves half a key list behind. A store without
+// a path lives in memory only.
+func (s *Store) save() error {
+	if s.path == "" {
+		return nil
+	}
+	list := make([]*Key, 0, len(s.keys))
+	for _, k := range s.keys {
+		list = append(list, k)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].CreatedAt.Before(list[j].CreatedAt) })
+	raw, err := json.MarshalIndent(list, "", "  ")
+	if err != nil {
+		return err
+	}
+	if dir := filepath.Dir(s.path); dir != "" {
+		if err := os.MkdirAll(dir, 0o700); err != nil {
+			return err
+		}
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, raw, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path)
+}
+
+func hashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+func randomString(length int) string {
+	b := make([]byte, length)
+	for i := range b {
+		n, _ := rand.Int(rand.Reader, big.NewInt(int64(len(charset))))
+		b[i] = charset[n.Int64()]
+	}
+	return string(b)
+}
@@ -0,0 +1,186 @@
+package keys
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/samber/lo"
+)
+
+type Key struct {
+	ID              string            `json:"id"`
+	Name            string            `json:"name"`
+	Prefix          string            `json:"prefix"`
+	SecretHash      string            `json:"secret_hash,omitempty"`
+	AllowedModels   []string          `json:"allowed_models,omitempty"`
+	ReasoningFormat string            `json:"reasoning_format,omitempty"`
+	ExpiresAt       *time.Time        `json:"expires_at,omitempty"`
+	Disabled        bool              `json:"disabled"`
+	Metadata        map[string]string `json:"metadata,omitempty"`
+	RateLimit       *RateLimit        `json:"rate_limit,omitempty"`
+	Quotas          []Quota           `json:"quotas,omitempty"`
+	NoTranscripts   bool              `json:"no_transcripts,omitempty"`
+	CreatedAt       time.Time         `json:"created_at"`
+	RotatedAt       *time.Time        `json:"rotated_at,omitempty"`
+}
+
+// RateLimit overrides the server wide limits for one key. Zero keeps the
+// server default, a negative value lifts the limit.
+type RateLimit struct {
+	RequestsPerMinute    int `json:"requests_per_minute" yaml:"requests_per_minute" toml:"requests_per_minute"`
+	TokensPerMinute      int `json:"tokens_per_minute" yaml:"tokens_per_minute" toml:"tokens_per_minute"`
+	MaxConcurrentStreams int `json:"max_concurrent_streams" yaml:"max_concurrent_streams" toml:"max_concurrent_streams"`
+}
+
+// Merge applies the overrides in r on top of defaults.
+func (r *RateLimit) Merge(defaults RateLimit) RateLimit {
+	if r == nil {
+		return defaults
+	}
+	pick := func(override, def int) int {
+		if override == 0 {
+			return def
+		}
+		return override
+	}
+	return RateLimit{
+		RequestsPerMinute:    pick(r.RequestsPerMinute, defaults.RequestsPerMinute),
+		TokensPerMinute:      pick(r.TokensPerMinute, defaults.TokensPerMinute),
+		MaxConcurrentStreams: pick(r.MaxConcurrentStreams, defaults.MaxConcurrentStreams),
+	}
+}
+
+// Quota caps what a key may spend on a group of models within a day or a
+// calendar month (UTC). No models means every model, a trailing * matches a
+// prefix, e.g. "openai-o*" for a reasoning tier.
+type Quota struct {
+	Period      string   `json:"period" yaml:"period" toml:"period" binding:"oneof=day month"`
+	Models      []string `json:"models,omitempty" yaml:"models" toml:"models"`
+	MaxRequests int      `json:"max_requests,omitempty" yaml:"max_requests" toml:"max_requests"`
+	MaxTokens   int      `json:"max_tokens,omitempty" yaml:"max_tokens" toml:"max_tokens"`
+	// DowngradeTo is used instead of the requested model once the quota is spent
+	DowngradeTo string `json:"downgrade_to,omitempty" yaml:"downgrade_to" toml:"downgrade_to"`
+}
+
+func (q Quota) Matches(model string) bool {
+	if len(q.Models) == 0 {
+		return true
+	}
+	for _, m := range q.Models {
+		if m == model || (strings.HasSuffix(m, "*") && strings.HasPrefix(model, strings.TrimSuffix(m, "*"))) {
+			return true
+		}
+	}
+	return false
+}
+
+// Start returns when the period containing now began.
+func (q Quota) Start(now time.Time) time.Time {
+	now = now.UTC()
+	if q.Period == "month" {
+		return time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	}
+	return time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// AllowsModel reports whether the key may use model, no list means any model.
+func (k Key) AllowsModel(model string) bool {
+	return len(k.AllowedModels) == 0 || lo.Contains(k.AllowedModels, model)
+}
+
+func (k Key) Expired(now time.Time) bool {
+	return k.ExpiresAt != nil && now.After(*k.ExpiresAt)
+}
+
+func (k Key) Active(now time.Time) bool {
+	return !k.Disabled && !k.Expired(now)
+}
+
+// Public strips the hash before the key leaves the server.
+func (k Key) Public() Key {
+	k.SecretHash = ""
+	return k
+}
+
+// Overrides are per key settings from the config file, they win over what
+// the key store says.
+type Overrides struct {
+	AllowedModels   []string   `json:"allowed_models,omitempty" yaml:"allowed_models" toml:"allowed_models"`
+	ReasoningFormat string     `json:"reasoning_format,omitempty" yaml:"reasoning_format" toml:"reasoning_format"`
+	RateLimit       *RateLimit `json:"rate_limit,omitempty" yaml:"rate_limit" toml:"rate_limit"`
+	Quotas          []Quota    `json:"quotas,omitempty" yaml:"quotas" toml:"quotas"`
+	NoTranscripts   *bool      `json:"no_transcripts,omitempty" yaml:"no_transcripts" toml:"no_transcripts"`
+}
+
+func (o Overrides) Validate() error {
+	if _, ok := ParseReasoningFormat(o.ReasoningFormat); o.ReasoningFormat != "" && !ok {
+		return fmt.Errorf("reasoning_format must be field, think or hide, got %q", o.ReasoningFormat)
+	}
+	for i, q := range o.Quotas {
+		if q.Period != "day" && q.Period != "month" {
+			return fmt.Errorf("quotas[%d]: period must be day or month, got %q", i, q.Period)
+		}
+	}
+	return nil
+}
+
+// WithOverrides applies the overrides configured for the key's id or,
+// failing that, its name.
+func (k Key) WithOverrides(all map[string]Overrides) Key {
+	o, ok := all[k.ID]
+	if !ok {
+		if o, ok = all[k.Name]; !ok {
+			return k
+		}
+	}
+	if o.AllowedModels != nil {
+		k.AllowedModels = o.AllowedModels
+	}
+	if o.ReasoningFormat != "" {
+		k.ReasoningFormat = o.ReasoningFormat
+	}
+	if o.RateLimit != nil {
+		limits := o.RateLimit.Merge(lo.FromPtr(k.RateLimit))
+		k.RateLimit = &limits
+	}
+	if o.Quotas != nil {
+		k.Quotas = o.Quotas
+	}
+	if o.NoTranscripts != nil {
+		k.NoTranscripts = *o.NoTranscripts
+	}
+	return k
+}
+
+type CreateRequest struct {
+	Name            string            `json:"name" binding:"required"`
+	AllowedModels   []string          `json:"allowed_models"`
+	ReasoningFormat string            `json:"reasoning_format"`
+	ExpiresAt       *time.Time        `json:"expires_at"`
+	Metadata        map[string]string `json:"metadata"`
+	RateLimit       *RateLimit        `json:"rate_limit"`
+	Quotas          []Quota           `json:"quotas" binding:"dive"`
+	NoTranscripts   bool              `json:"no_transcripts"`
+}
+
+// UpdateRequest only touches the fields that are set.
+type UpdateRequest struct {
+	Name            *string            `json:"name"`
+	AllowedModels   *[]string          `json:"allowed_models"`
+	ReasoningFormat *string            `json:"reasoning_format"`
+	ExpiresAt       *time.Time         `json:"expires_at"`
+	ClearExpiry     bool               `json:"clear_expiry"`
+	Disabled        *bool              `json:"disabled"`
+	Metadata        *map[string]string `json:"metadata"`
+	RateLimit       *RateLimit         `json:"rate_limit"`
+	Quotas          *[]Quota           `json:"quotas" binding:"omitempty,dive"`
+	NoTranscripts   *bool              `json:"no_transcripts"`
+}
+
+// KeyWithSecret is only ever returned on create and rotate, the secret is not
+// stored and can't be shown again.
+type KeyWithSecret struct {
+	Key
+	Secret string `json:"secret"`
+}
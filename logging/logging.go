@@ -0,0 +1,100 @@
+package logging
+
+import (
+	"crypto/rand"
+	"math/big"
+	"regexp"
+	"time"
+
+	"github.com/Ken288yzs1/raychat/keys"
+	"github.com/Ken288yzs1/raychat/settings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	RequestIDHeader = "X-Request-ID"
+	requestIDKey    = "raychat/request_id"
+	charset         = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+)
+
+// incoming ids are reused as part of the completion id, keep them boring
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9_-]{8,64}$`)
+
+// Setup applies LOG_FORMAT, LOG_LEVEL and LOG_REDACT_CONTENT to the global logger.
+func Setup(conf settings.RayConfig) {
+	var formatter logrus.Formatter = &logrus.TextFormatter{}
+	if conf.LogFormat == "json" {
+		formatter = &logrus.JSONFormatter{}
+	}
+	logrus.SetFormatter(&redactingFormatter{next: formatter, content: conf.LogRedactContent})
+
+	level, err := logrus.ParseLevel(conf.LogLevel)
+	if err != nil {
+		logrus.WithError(err).Warn("unknown log level, fall back to info")
+		level = logrus.InfoLevel
+	}
+	if conf.Debug {
+		level = logrus.DebugLevel
+	}
+	logrus.SetLevel(level)
+}
+
+// RequestID reuses a sane incoming X-Request-ID or makes one up, and echoes
+// it on the response.
+func RequestID(c *gin.Context) {
+	id := c.GetHeader(RequestIDHeader)
+	if !validRequestID.MatchString(id) {
+		id = generateID(29)
+	}
+	c.Set(requestIDKey, id)
+	c.Header(RequestIDHeader, id)
+	c.Next()
+}
+
+func GetRequestID(c *gin.Context) string {
+	return c.GetString(requestIDKey)
+}
+
+// AccessLog writes one structured line per request once it is done.
+func AccessLog(c *gin.Context) {
+	start := time.Now()
+	c.Next()
+
+	fields := logrus.Fields{
+		"request_id": GetRequestID(c),
+		"method":     c.Request.Method,
+		"path":       c.Request.URL.Path,
+		"route":      c.FullPath(),
+		"status":     c.Writer.Status(),
+		"latency_ms": time.Since(start).Milliseconds(),
+		"bytes":      c.Writer.Size(),
+		"client_ip":  c.ClientIP(),
+		"user_agent": c.Request.UserAgent(),
+	}
+	if key, ok := keys.FromContext(c); ok {
+		fields["key_id"] = key.ID
+	}
+	entry := logrus.WithField("prefix", "access").WithFields(fields)
+	if len(c.Errors) > 0 {
+		entry = entry.WithField("errors", c.Errors.String())
+	}
+	switch status := c.Writer.Status(); {
+	case status >= 500:
+		entry.Error("request")
+	case status >= 400:
+		entry.Warn("request")
+	default:
+		entry.Info("request")
+	}
+}
+
+func generateID(length int) string {
+	b := make([]byte, length)
+	for i := range b {
+		n, _ := rand.Int(rand.Reader, big.NewInt(int64(len(charset))))
+		b[i] = charset[n.Int64()]
+	}
+	return string(b)
+}
@@ -0,0 +1,67 @@
+package logging
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/sirupsen/logrus"
+)
+
+const mask = "[REDACTED]"
+
+var (
+	secretPatterns = []*regexp.Regexp{
+		regexp.MustCompile(`(?i)(bearer\s+)[A-Za-z0-9._~+/=-]+`),
+		regexp.MustCompile(`sk-[A-Za-z0-9_-]{8,}`),
+		regexp.MustCompile(`(?i)("(?:password|access_token|refresh_token|client_secret|token|secret)"\s*:\s*")[^"]*(")`),
+		regexp.MustCompile(`(?i)((?:password|access_token|client_secret|code)=)[^&\s]+`),
+	}
+	emailPattern   = regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`)
+	contentPattern = regexp.MustCompile(`("(?:content|text|reasoning|reasoning_content|additional_system_instructions)"\s*:\s*")(?:[^"\\]|\\.)*(")`)
+)
+
+// Redact masks credentials and email addresses in s, and message content
+// when content is true.
+func Redact(s string, content bool) string {
+	for _, p := range secretPatterns {
+		s = p.ReplaceAllStringFunc(s, func(m string) string {
+			sub := p.FindStringSubmatch(m)
+			switch len(sub) {
+			case 2:
+				return sub[1] + mask
+			case 3:
+				return sub[1] + mask + sub[2]
+			}
+			return mask
+		})
+	}
+	s = emailPattern.ReplaceAllString(s, mask)
+	if content {
+		s = contentPattern.ReplaceAllString(s, "${1}"+mask+"${2}")
+	}
+	return s
+}
+
+// redactingFormatter scrubs every entry before the real formatter sees it,
+// so no call site can leak a secret by accident.
+type redactingFormatter struct {
+	next    logrus.Formatter
+	content bool
+}
+
+func (f *redactingFormatter) Format(entry *logrus.Entry) ([]byte, error) {
+	clean := entry.Dup()
+	clean.Level, clean.Caller = entry.Level, entry.Caller
+	clean.Message = Redact(entry.Message, f.content)
+	for k, v := range clean.Data {
+		switch v := v.(type) {
+		case string:
+			clean.Data[k] = Redact(v, f.content)
+		case error:
+			clean.Data[k] = Redact(v.Error(), f.content)
+		case fmt.Stringer:
+			clean.Data[k] = Redact(v.String(), f.content)
+		}
+	}
+	return f.next.Format(clean)
+}
@@ -0,0 +1,61 @@
+package logging
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestRedact(t *testing.T) {
+	tests := []struct {
+		name    string
+		in      string
+		content bool
+		want    string
+	}{
+		{name: "bearer", in: "Authorization: Bearer abc.DEF-123", want: "Authorization: Bearer [REDACTED]"},
+		{name: "bearer any case", in: "authorization: bearer abc", want: "authorization: bearer [REDACTED]"},
+		{name: "api key", in: "key sk-raychat-6CIwPL5ILQk9 rejected", want: "key [REDACTED] rejected"},
+		{name: "short sk- words stay", in: "sk-abc", want: "sk-abc"},
+		{name: "json password", in: `{"email_hint":"x","password": "hunter2","n":1}`, want: `{"email_hint":"x","password": "[REDACTED]","n":1}`},
+		{name: "json token", in: `{"access_token":"t0k3n","refresh_token":"r"}`, want: `{"access_token":"[REDACTED]","refresh_token":"[REDACTED]"}`},
+		{name: "form", in: "grant_type=password&password=hunter2&client_secret=s3cr3t&code=42", want: "grant_type=password&password=[REDACTED]&client_secret=[REDACTED]&code=[REDACTED]"},
+		{name: "email", in: "login failed for jane.doe+ai@example.co.uk", want: "login failed for [REDACTED]"},
+		{name: "content kept", in: `{"role":"user","content":"hi there"}`, want: `{"role":"user","content":"hi there"}`},
+		{name: "content", in: `{"role":"user","content":"hi there"}`, content: true, want: `{"role":"user","content":"[REDACTED]"}`},
+		{name: "escaped quotes", in: `{"text":"say \"hi\"","model":"o3"}`, content: true, want: `{"text":"[REDACTED]","model":"o3"}`},
+		{name: "reasoning fields", in: `{"reasoning":"a","reasoning_content":"b","additional_system_instructions":"c"}`, content: true,
+			want: `{"reasoning":"[REDACTED]","reasoning_content":"[REDACTED]","additional_system_instructions":"[REDACTED]"}`},
+		{name: "nothing to hide", in: "listening on :7860", want: "listening on :7860"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Redact(tt.in, tt.content); got != tt.want {
+				t.Errorf("Redact(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRedactingFormatter(t *testing.T) {
+	var out bytes.Buffer
+	l := logrus.New()
+	l.SetOutput(&out)
+	l.SetFormatter(&redactingFormatter{next: &logrus.JSONFormatter{}})
+	l.WithError(errors.New("bad token sk-0123456789ab")).
+		WithField("email", "jane@example.com").
+		Warn("Bearer abc rejected")
+
+	got := out.String()
+	for _, leak := range []string{"sk-0123456789ab", "jane@example.com", "abc rejected"} {
+		if strings.Contains(got, leak) {
+			t.Errorf("%q leaked: %s", leak, got)
+		}
+	}
+	if !strings.Contains(got, `"level":"warning"`) {
+		t.Errorf("level lost: %s", got)
+	}
+}
@@ -1,7 +1,11 @@
 package main
 
-import "raychat/service"
+import (
+	"os"
+
+	"github.com/Ken288yzs1/raychat/cli"
+)
 
 func main() {
-	service.Run()
+	os.Exit(cli.Run(os.Args[1:]))
 }
@@ -0,0 +1,129 @@
+package metrics
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const (
+	namespace = "raychat"
+	modelKey  = "raychat/metrics_model"
+)
+
+var (
+	Requests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "HTTP requests by route, model and status code.",
+	}, []string{"route", "model", "status"})
+
+	RequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "Time spent serving HTTP requests, streams included.",
+		Buckets:   []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 120, 300},
+	}, []string{"route", "status"})
+
+	UpstreamLatency = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "upstream_latency_seconds",
+		Help:      "Time until raycast answered with response headers.",
+		Buckets:   prometheus.ExponentialBuckets(0.05, 2, 12),
+	}, []string{"model"})
+
+	UpstreamTTFT = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "upstream_time_to_first_token_seconds",
+		Help:      "Time from the upstream request until the first text or reasoning token.",
+		Buckets:   prometheus.ExponentialBuckets(0.1, 2, 12),
+	}, []string{"model"})
+
+	UpstreamErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "upstream_errors_total",
+		Help:      "Failed upstream requests by model.",
+	}, []string{"model"})
+
+	Tokens = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "tokens_total",
+		Help:      "Estimated tokens by model and kind (prompt, completion, reasoning).",
+	}, []string{"model", "kind"})
+
+	ActiveStreams = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "active_streams",
+		Help:      "Streams currently being relayed.",
+	})
+
+	AuthAttempts = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "auth_attempts_total",
+		Help:      "Raycast login and token refresh attempts by result.",
+	}, []string{"op", "result"})
+
+	ModelRefreshes = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "model_catalog_refreshes_total",
+		Help:      "Model catalog fetches from raycast by result.",
+	}, []string{"result"})
+
+	ModelRefreshTimestamp = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "model_catalog_last_refresh_timestamp_seconds",
+		Help:      "Unix time of the last successful model catalog fetch.",
+	})
+
+	ModelsAvailable = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "model_catalog_models",
+		Help:      "Models in the catalog.",
+	})
+
+	Rejections = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "rejections_total",
+		Help:      "Requests refused by rate limits and quotas, by reason.",
+	}, []string{"reason"})
+)
+
+// Result turns an error into the result label.
+func Result(err error) string {
+	if err != nil {
+		return "failure"
+	}
+	return "success"
+}
+
+// OtherModel is the model label of every model outside the catalog, so
+// that clients can't blow up the cardinality with made up model names.
+const OtherModel = "other"
+
+// SetModel labels the current request with the model it ended up using.
+func SetModel(c *gin.Context, model string) {
+	c.Set(modelKey, model)
+}
+
+// Middleware counts and times every request. Unmatched routes are folded
+// into one label to keep scanners from blowing up the cardinality.
+func Middleware(c *gin.Context) {
+	start := time.Now()
+	c.Next()
+
+	route := c.FullPath()
+	if route == "" {
+		route = "unmatched"
+	}
+	status := strconv.Itoa(c.Writer.Status())
+	Requests.WithLabelValues(route, c.GetString(modelKey), status).Inc()
+	RequestDuration.WithLabelValues(route, status).Observe(time.Since(start).Seconds())
+}
+
+func Handler() gin.HandlerFunc {
+	return gin.WrapH(promhttp.Handler())
+}
@@ -1,29 +1,66 @@
 package middlewares
 
 import (
-	"raychat/settings"
+	"crypto/subtle"
 	"strings"
 
+	"github.com/Ken288yzs1/raychat/keys"
+	"github.com/Ken288yzs1/raychat/settings"
+
 	"github.com/gin-gonic/gin"
 	"github.com/samber/lo"
 )
 
-func Auth(c *gin.Context) {
-	if len(settings.Get().ExternalToken) == 0 {
+// Auth checks the bearer token against EXTERNAL_TOKEN and the keys in store,
+// everyone is let in when there are none.
+func Auth(store *keys.Store) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if len(settings.Get().ExternalToken) == 0 && store.Len() == 0 {
+			c.Next()
+			return
+		}
+
+		token, ok := bearerToken(c)
+		if !ok {
+			c.AbortWithStatusJSON(401, gin.H{"message": "Unauthorized"})
+			return
+		}
+		if idx := lo.IndexOf(settings.Get().ExternalToken, token); idx >= 0 {
+			keys.SetContext(c, keys.FromExternalToken(idx, token).WithOverrides(settings.Get().Keys))
+			c.Next()
+			return
+		}
+		key, err := store.Lookup(token)
+		if err != nil {
+			c.AbortWithStatusJSON(401, gin.H{"message": "Unauthorized", "error": err.Error()})
+			return
+		}
+		keys.SetContext(c, key.WithOverrides(settings.Get().Keys))
 		c.Next()
-		return
 	}
+}
 
-	rawtoken := c.GetHeader("Authorization")
-	tokenStrlist := strings.Split(rawtoken, " ")
-	if len(tokenStrlist) != 2 || len(rawtoken) == 0 {
-		c.AbortWithStatusJSON(401, gin.H{"message": "Unauthorized"})
+// Admin guards the key management api with ADMIN_TOKEN, the api does not
+// exist at all when no admin token is configured.
+func Admin(c *gin.Context) {
+	adminToken := settings.Get().AdminToken
+	if adminToken == "" {
+		c.AbortWithStatusJSON(404, gin.H{"message": "admin api is disabled"})
 		return
 	}
-	token := tokenStrlist[1]
-	if !lo.Contains(settings.Get().ExternalToken, token) {
+	token, ok := bearerToken(c)
+	if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(adminToken)) != 1 {
 		c.AbortWithStatusJSON(401, gin.H{"message": "Unauthorized"})
 		return
 	}
 	c.Next()
 }
+
+func bearerToken(c *gin.Context) (string, bool) {
+	rawtoken := c.GetHeader("Authorization")
+	tokenStrlist := strings.Split(rawtoken, " ")
+	if len(tokenStrlist) != 2 || len(rawtoken) == 0 {
+		return "", false
+	}
+	return tokenStrlist[1], true
+}
@@ -0,0 +1,85 @@
+package middlewares
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/Ken288yzs1/raychat/keys"
+	"github.com/Ken288yzs1/raychat/metrics"
+	"github.com/Ken288yzs1/raychat/ratelimit"
+	"github.com/Ken288yzs1/raychat/settings"
+	"github.com/Ken288yzs1/raychat/usage"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RateLimit enforces per key request, token and concurrent stream limits. It
+// must run after Auth so the key is known, anonymous callers are limited per ip.
+func RateLimit(limiter *ratelimit.Limiter) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key, ok := keys.FromContext(c)
+		limits := key.RateLimit.Merge(keys.RateLimit{
+			RequestsPerMinute:    settings.Get().RateLimitRPM,
+			TokensPerMinute:      settings.Get().RateLimitTPM,
+			MaxConcurrentStreams: settings.Get().RateLimitStreams,
+		})
+		if limits.RequestsPerMinute <= 0 && limits.TokensPerMinute <= 0 && limits.MaxConcurrentStreams <= 0 {
+			c.Next()
+			return
+		}
+		id := key.ID
+		if !ok {
+			id = "ip:" + c.ClientIP()
+		}
+
+		body, _ := io.ReadAll(c.Request.Body)
+		c.Request.Body = io.NopCloser(bytes.NewBuffer(body))
+		var peek struct {
+			Stream bool `json:"stream"`
+		}
+		json.Unmarshal(body, &peek)
+		// the raw body is a rough upper bound of the prompt, charge it up front and
+		// settle with the real usage once the completion is done
+		estimate := (len(body) + 3) / 4
+
+		res := limiter.Acquire(id, limits, estimate, peek.Stream)
+		setRateLimitHeaders(c, res)
+		if !res.Allowed {
+			metrics.Rejections.WithLabelValues("rate_limit_" + res.Reason).Inc()
+			c.Header("Retry-After", strconv.Itoa(int(math.Ceil(res.RetryAfter.Seconds()))))
+			c.AbortWithStatusJSON(429, gin.H{"error": gin.H{
+				"message": fmt.Sprintf("rate limit reached for %s, retry in %s", res.Reason, res.RetryAfter.Round(time.Millisecond)),
+				"type":    res.Reason,
+				"code":    "rate_limit_exceeded",
+			}})
+			return
+		}
+		if peek.Stream {
+			defer limiter.Release(id)
+		}
+
+		c.Next()
+
+		if used, ok := usage.FromContext(c); ok {
+			limiter.Charge(id, used.Total()-estimate)
+		}
+	}
+}
+
+func setRateLimitHeaders(c *gin.Context, res ratelimit.Result) {
+	if res.Limits.RequestsPerMinute > 0 {
+		c.Header("x-ratelimit-limit-requests", strconv.Itoa(res.Limits.RequestsPerMinute))
+		c.Header("x-ratelimit-remaining-requests", strconv.Itoa(res.RemainingRequests))
+		c.Header("x-ratelimit-reset-requests", res.ResetRequests.Round(time.Millisecond).String())
+	}
+	if res.Limits.TokensPerMinute > 0 {
+		c.Header("x-ratelimit-limit-tokens", strconv.Itoa(res.Limits.TokensPerMinute))
+		c.Header("x-ratelimit-remaining-tokens", strconv.Itoa(res.RemainingTokens))
+		c.Header("x-ratelimit-reset-tokens", res.ResetTokens.Round(time.Millisecond).String())
+	}
+}
@@ -0,0 +1,176 @@
+package quota
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/Ken288yzs1/raychat/keys"
+	"github.com/Ken288yzs1/raychat/settings"
+	"github.com/Ken288yzs1/raychat/transport"
+	"github.com/Ken288yzs1/raychat/usage"
+
+	"github.com/samber/lo"
+	"github.com/sirupsen/logrus"
+)
+
+func Logger() *logrus.Entry {
+	return logrus.WithField("prefix", "quota")
+}
+
+// Status is how much of one quota has been used in the current period.
+type Status struct {
+	Quota    keys.Quota
+	Since    time.Time
+	Requests int
+	Tokens   int
+}
+
+// Ratio is the share of the quota used, by whichever limit is closer.
+func (s Status) Ratio() float64 {
+	ratio := 0.0
+	if s.Quota.MaxRequests > 0 {
+		ratio = float64(s.Requests) / float64(s.Quota.MaxRequests)
+	}
+	if s.Quota.MaxTokens > 0 {
+		ratio = max(ratio, float64(s.Tokens)/float64(s.Quota.MaxTokens))
+	}
+	return ratio
+}
+
+func (s Status) Exceeded() bool {
+	return s.Ratio() >= 1
+}
+
+// counter is what a key spent on one of its quotas in the current period.
+type counter struct {
+	since    time.Time
+	requests int
+	tokens   int
+	warned   bool
+}
+
+// Tracker keeps what every key spent on its quotas, seeded from the ledger
+// and brought up to date by Record.
+type Tracker struct {
+	ledger *usage.Ledger
+	now    func() time.Time
+
+	mu sync.Mutex
+	// counters by key id and quota, seeded from the ledger on first use
+	counters map[string]*counter
+}
+
+func New(ledger *usage.Ledger) *Tracker {
+	return &Tracker{ledger: ledger, now: time.Now, counters: map[string]*counter{}}
+}
+
+func counterID(key keys.Key, q keys.Quota) string {
+	return fmt.Sprintf("%s/%s/%v", key.ID, q.Period, q.Models)
+}
+
+// counterFor returns the counter of q for the current period, starting a
+// new one when the period has changed. Callers hold t.mu.
+func (t *Tracker) counterFor(key keys.Key, q keys.Quota, now time.Time) *counter {
+	id := counterID(key, q)
+	since := q.Start(now)
+	c, ok := t.counters[id]
+	if !ok || !c.since.Equal(since) {
+		c = &counter{since: since}
+		c.requests, c.tokens = t.ledger.Usage(key.ID, since, q.Matches)
+		t.counters[id] = c
+	}
+	return c
+}
+
+func (c *counter) status(q keys.Quota) Status {
+	return Status{Quota: q, Since: c.since, Requests: c.requests, Tokens: c.tokens}
+}
+
+// Check returns the first of the key's quotas on model that is spent, or nil.
+// It only looks, candidates can be checked without side effects.
+func (t *Tracker) Check(key keys.Key, model string) *Status {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	now := t.now()
+	for _, q := range key.Quotas {
+		if !q.Matches(model) {
+			continue
+		}
+		if status := t.counterFor(key, q, now).status(q); status.Exceeded() {
+			return &status
+		}
+	}
+	return nil
+}
+
+// Record adds the completion r of key to the ledger and the key's quotas.
+// Quotas crossing QUOTA_WARN_RATIO are reported once per period.
+func (t *Tracker) Record(key keys.Key, r usage.Record) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	now := t.now()
+	// the counters are brought up to date before r is in the ledger, so that
+	// a counter seeded now doesn't count it twice
+	quotas := lo.Filter(key.Quotas, func(q keys.Quota, _ int) bool { return q.Matches(r.Model) })
+	touched := lo.Map(quotas, func(q keys.Quota, _ int) *counter { return t.counterFor(key, q, now) })
+	t.ledger.Add(r)
+	for i, c := range touched {
+		c.requests++
+		c.tokens += r.Tokens.Total()
+		if status := c.status(quotas[i]); !c.warned && status.Ratio() >= settings.Get().QuotaWarnRatio {
+			c.warned = true
+			warn(key, status)
+		}
+	}
+}
+
+func warn(key keys.Key, status Status) {
+	event := map[string]any{
+		"event":        "quota_warning",
+		"key_id":       key.ID,
+		"key_name":     key.Name,
+		"period":       status.Quota.Period,
+		"models":       status.Quota.Models,
+		"since":        status.Since,
+		"requests":     status.Requests,
+		"max_requests": status.Quota.MaxRequests,
+		"tokens":       status.Tokens,
+		"max_tokens":   status.Quota.MaxTokens,
+		"ratio":        status.Ratio(),
+	}
+	Logger().WithFields(event).Warnf("api key %q used %.0f%% of its %s quota", key.Name, status.Ratio()*100, status.Quota.Period)
+
+	if url := settings.Get().QuotaWebhookURL; url != "" {
+		go postWebhook(url, event)
+	}
+}
+
+func postWebhook(url string, event map[string]any) {
+	raw, err := json.Marshal(event)
+	if err != nil {
+		Logger().WithError(err).Error("marshal quota webhook failed")
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(raw))
+	if err != nil {
+		Logger().WithError(err).Error("build quota webhook failed")
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := transport.Client().Do(req)
+	if err != nil {
+		Logger().WithError(err).Error("send quota webhook failed")
+		return
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		Logger().WithField("status code", resp.StatusCode).Error("quota webhook rejected")
+	}
+}
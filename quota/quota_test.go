@@ -0,0 +1,173 @@
+package quota
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Ken288yzs1/raychat/keys"
+	"github.com/Ken288yzs1/raychat/settings"
+	"github.com/Ken288yzs1/raychat/usage"
+)
+
+// newTestTracker returns a tracker over an in-memory ledger whose clock is
+// moved by setting *now.
+func newTestTracker(t *testing.T, now *time.Time) *Tracker {
+	t.Helper()
+	ledger, err := usage.Open("", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tracker := New(ledger)
+	tracker.now = func() time.Time { return *now }
+	return tracker
+}
+
+func record(tracker *Tracker, key keys.Key, now time.Time, model string, tokens int) {
+	tracker.Record(key, usage.Record{Time: now, KeyID: key.ID, Model: model, Tokens: usage.Tokens{Prompt: tokens}})
+}
+
+func TestCheck(t *testing.T) {
+	now := time.Date(2024, 5, 10, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name    string
+		quota   keys.Quota
+		records []int
+		model   string
+		// wantRequests is zero when the quota is not spent
+		wantRequests int
+		wantTokens   int
+	}{
+		{name: "under both limits", quota: keys.Quota{Period: "day", MaxRequests: 3, MaxTokens: 100}, records: []int{10, 10}, model: "gpt-4o"},
+		{name: "requests spent", quota: keys.Quota{Period: "day", MaxRequests: 3}, records: []int{1, 1, 1}, model: "gpt-4o", wantRequests: 3, wantTokens: 3},
+		{name: "tokens spent", quota: keys.Quota{Period: "month", MaxTokens: 100}, records: []int{60, 40}, model: "gpt-4o", wantRequests: 2, wantTokens: 100},
+		{name: "other models don't count", quota: keys.Quota{Period: "day", MaxRequests: 1, Models: []string{"o3*"}}, records: []int{1}, model: "gpt-4o"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tracker := newTestTracker(t, &now)
+			key := keys.Key{ID: "key_1", Quotas: []keys.Quota{tt.quota}}
+			for _, tokens := range tt.records {
+				record(tracker, key, now, "gpt-4o", tokens)
+			}
+			status := tracker.Check(key, tt.model)
+			if tt.wantRequests == 0 {
+				if status != nil {
+					t.Fatalf("spent: %+v", status)
+				}
+				return
+			}
+			if status == nil {
+				t.Fatal("quota not spent")
+			}
+			if status.Requests != tt.wantRequests || status.Tokens != tt.wantTokens || !status.Since.Equal(tt.quota.Start(now)) {
+				t.Errorf("status = %+v", status)
+			}
+		})
+	}
+}
+
+func TestSeedFromLedger(t *testing.T) {
+	now := time.Date(2024, 5, 10, 12, 0, 0, 0, time.UTC)
+	ledger, err := usage.Open("", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// yesterday is outside the day quota, earlier today is not
+	ledger.Add(usage.Record{Time: now.Add(-24 * time.Hour), KeyID: "key_1", Model: "gpt-4o"})
+	ledger.Add(usage.Record{Time: now.Add(-time.Hour), KeyID: "key_1", Model: "gpt-4o"})
+	tracker := New(ledger)
+	tracker.now = func() time.Time { return now }
+
+	key := keys.Key{ID: "key_1", Quotas: []keys.Quota{{Period: "day", MaxRequests: 2}}}
+	if status := tracker.Check(key, "gpt-4o"); status != nil {
+		t.Fatalf("spent after one request today: %+v", status)
+	}
+	record(tracker, key, now, "gpt-4o", 1)
+	if status := tracker.Check(key, "gpt-4o"); status == nil || status.Requests != 2 {
+		t.Errorf("status = %+v, want 2 requests", status)
+	}
+}
+
+func TestPeriodRollover(t *testing.T) {
+	now := time.Date(2024, 5, 31, 23, 0, 0, 0, time.UTC)
+	tracker := newTestTracker(t, &now)
+	key := keys.Key{ID: "key_1", Quotas: []keys.Quota{
+		{Period: "day", MaxRequests: 1, Models: []string{"gpt-4o"}},
+		{Period: "month", MaxRequests: 2, Models: []string{"o3"}},
+	}}
+	record(tracker, key, now, "gpt-4o", 1)
+	record(tracker, key, now, "o3", 1)
+	record(tracker, key, now, "o3", 1)
+	if tracker.Check(key, "gpt-4o") == nil || tracker.Check(key, "o3") == nil {
+		t.Fatal("quotas not spent")
+	}
+
+	now = now.Add(2 * time.Hour)
+	if status := tracker.Check(key, "gpt-4o"); status != nil {
+		t.Errorf("day quota still spent the next day: %+v", status)
+	}
+	if status := tracker.Check(key, "o3"); status != nil {
+		t.Errorf("month quota still spent the next month: %+v", status)
+	}
+}
+
+func TestDowngradeTo(t *testing.T) {
+	now := time.Date(2024, 5, 10, 12, 0, 0, 0, time.UTC)
+	tracker := newTestTracker(t, &now)
+	key := keys.Key{ID: "key_1", Quotas: []keys.Quota{{Period: "day", MaxRequests: 1, Models: []string{"o3"}, DowngradeTo: "gpt-4o"}}}
+	record(tracker, key, now, "o3", 1)
+	status := tracker.Check(key, "o3")
+	if status == nil || status.Quota.DowngradeTo != "gpt-4o" {
+		t.Fatalf("status = %+v, want a downgrade to gpt-4o", status)
+	}
+	if status := tracker.Check(key, "gpt-4o"); status != nil {
+		t.Errorf("downgrade target spent: %+v", status)
+	}
+}
+
+func TestWarnWebhookOnce(t *testing.T) {
+	posts := make(chan map[string]any, 10)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var event map[string]any
+		json.NewDecoder(r.Body).Decode(&event)
+		posts <- event
+	}))
+	defer srv.Close()
+	old := settings.Get()
+	settings.Set(settings.RayConfig{QuotaWarnRatio: 0.5, QuotaWebhookURL: srv.URL})
+	defer settings.Set(old)
+
+	now := time.Date(2024, 5, 10, 12, 0, 0, 0, time.UTC)
+	tracker := newTestTracker(t, &now)
+	key := keys.Key{ID: "key_1", Name: "ci", Quotas: []keys.Quota{{Period: "day", MaxRequests: 4}}}
+	for i := 0; i < 3; i++ {
+		record(tracker, key, now, "gpt-4o", 1)
+	}
+
+	select {
+	case event := <-posts:
+		if event["event"] != "quota_warning" || event["key_id"] != "key_1" || event["requests"] != 2.0 {
+			t.Errorf("event = %v", event)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("no webhook")
+	}
+	select {
+	case event := <-posts:
+		t.Errorf("second webhook %v", event)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	// a new period warns again
+	now = now.Add(24 * time.Hour)
+	record(tracker, key, now, "gpt-4o", 1)
+	record(tracker, key, now, "gpt-4o", 1)
+	select {
+	case <-posts:
+	case <-time.After(2 * time.Second):
+		t.Error("no webhook in the new period")
+	}
+}
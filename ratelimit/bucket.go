@@ -0,0 +1,57 @@
+package ratelimit
+
+import (
+	"math"
+	"time"
+)
+
+// bucket is a token bucket refilled continuously, it may go negative when a
+// request turns out to cost more than it was admitted for.
+type bucket struct {
+	capacity float64
+	tokens   float64
+	rate     float64 // per second
+	last     time.Time
+}
+
+func newBucket(perMinute int, now time.Time) *bucket {
+	return &bucket{
+		capacity: float64(perMinute),
+		tokens:   float64(perMinute),
+		rate:     float64(perMinute) / 60,
+		last:     now,
+	}
+}
+
+func (b *bucket) refill(now time.Time) {
+	b.tokens = math.Min(b.capacity, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	b.last = now
+}
+
+// wait returns how long until n can be taken, zero when it can be taken now.
+// Requests larger than the bucket only wait for a full bucket.
+func (b *bucket) wait(n float64, now time.Time) time.Duration {
+	b.refill(now)
+	return b.until(math.Min(n, b.capacity))
+}
+
+func (b *bucket) charge(n float64, now time.Time) {
+	b.refill(now)
+	b.tokens -= n
+}
+
+func (b *bucket) remaining() int {
+	return int(math.Max(0, b.tokens))
+}
+
+// reset is how long until the bucket is full again.
+func (b *bucket) reset() time.Duration {
+	return b.until(b.capacity)
+}
+
+func (b *bucket) until(level float64) time.Duration {
+	if b.tokens >= level {
+		return 0
+	}
+	return time.Duration((level - b.tokens) / b.rate * float64(time.Second))
+}
@@ -0,0 +1,161 @@
+package ratelimit
+
+import (
+	"sync"
+	"time"
+
+	"github.com/Ken288yzs1/raychat/keys"
+)
+
+// Result describes the state of a key's limits after an admission attempt,
+// enough to fill the x-ratelimit-* headers.
+type Result struct {
+	Allowed    bool
+	Reason     string
+	RetryAfter time.Duration
+
+	Limits            keys.RateLimit
+	RemainingRequests int
+	RemainingTokens   int
+	ResetRequests     time.Duration
+	ResetTokens       time.Duration
+}
+
+// idleAfter is how often idle states are dropped, a minute is also how long
+// the per minute buckets take to fill up again.
+const idleAfter = time.Minute
+
+type state struct {
+	limits   keys.RateLimit
+	requests *bucket
+	tokens   *bucket
+	streams  int
+	used     time.Time
+}
+
+// idle is whether s is no different from a fresh state, no stream open and
+// the buckets full again.
+func (s *state) idle(now time.Time) bool {
+	if s.streams > 0 || now.Sub(s.used) < idleAfter {
+		return false
+	}
+	for _, b := range []*bucket{s.requests, s.tokens} {
+		if b != nil && b.wait(b.capacity, now) > 0 {
+			return false
+		}
+	}
+	return true
+}
+
+type Limiter struct {
+	mu     sync.Mutex
+	states map[string]*state
+	swept  time.Time
+	now    func() time.Time
+}
+
+func New() *Limiter {
+	return &Limiter{states: map[string]*state{}, now: time.Now}
+}
+
+// Acquire admits one request estimated at tokens for the key id. Admitted
+// streams hold a slot until Release.
+func (l *Limiter) Acquire(id string, limits keys.RateLimit, tokens int, stream bool) Result {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.sweep(now)
+	s := l.state(id, limits, now)
+	s.used = now
+	res := Result{Allowed: true, Limits: limits}
+
+	if stream && limits.MaxConcurrentStreams > 0 && s.streams >= limits.MaxConcurrentStreams {
+		res.Allowed, res.Reason, res.RetryAfter = false, "streams", time.Second
+	}
+	if res.Allowed && s.requests != nil {
+		if wait := s.requests.wait(1, now); wait > 0 {
+			res.Allowed, res.Reason, res.RetryAfter = false, "requests", wait
+		}
+	}
+	if res.Allowed && s.tokens != nil {
+		if wait := s.tokens.wait(float64(tokens), now); wait > 0 {
+			res.Allowed, res.Reason, res.RetryAfter = false, "tokens", wait
+		}
+	}
+	if res.Allowed {
+		if s.requests != nil {
+			s.requests.charge(1, now)
+		}
+		if s.tokens != nil {
+			s.tokens.charge(float64(tokens), now)
+		}
+		if stream {
+			s.streams++
+		}
+	}
+
+	if s.requests != nil {
+		res.RemainingRequests, res.ResetRequests = s.requests.remaining(), s.requests.reset()
+	}
+	if s.tokens != nil {
+		res.RemainingTokens, res.ResetTokens = s.tokens.remaining(), s.tokens.reset()
+	}
+	return res
+}
+
+// Release frees the stream slot taken by Acquire.
+func (l *Limiter) Release(id string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if s, ok := l.states[id]; ok && s.streams > 0 {
+		s.streams--
+	}
+}
+
+// Charge settles the difference between the admission estimate and what the
+// completion actually used, a negative n gives tokens back.
+func (l *Limiter) Charge(id string, n int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if s, ok := l.states[id]; ok && s.tokens != nil {
+		now := l.now()
+		s.tokens.charge(float64(n), now)
+		s.used = now
+	}
+}
+
+// state returns the buckets for id, rebuilding them when the key's limits
+// have been changed through the admin api.
+func (l *Limiter) state(id string, limits keys.RateLimit, now time.Time) *state {
+	s, ok := l.states[id]
+	if ok && s.limits == limits {
+		return s
+	}
+	next := &state{limits: limits}
+	if ok {
+		next.streams = s.streams
+	}
+	if limits.RequestsPerMinute > 0 {
+		next.requests = newBucket(limits.RequestsPerMinute, now)
+	}
+	if limits.TokensPerMinute > 0 {
+		next.tokens = newBucket(limits.TokensPerMinute, now)
+	}
+	l.states[id] = next
+	return next
+}
+
+// sweep drops idle states, anonymous callers get one per address and would
+// otherwise pile up forever.
+func (l *Limiter) sweep(now time.Time) {
+	if now.Sub(l.swept) < idleAfter {
+		return
+	}
+	l.swept = now
+	for id, s := range l.states {
+		if s.idle(now) {
+			delete(l.states, id)
+		}
+	}
+}
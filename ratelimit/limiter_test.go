@@ -0,0 +1,178 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Ken288yzs1/raychat/keys"
+)
+
+// clock is a fake time source the tests move by hand.
+type clock struct {
+	t time.Time
+}
+
+func (c *clock) now() time.Time {
+	return c.t
+}
+
+func (c *clock) advance(d time.Duration) {
+	c.t = c.t.Add(d)
+}
+
+func newTestLimiter() (*Limiter, *clock) {
+	c := &clock{t: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	l := New()
+	l.now = c.now
+	return l, c
+}
+
+func TestBucketWait(t *testing.T) {
+	tests := []struct {
+		name    string
+		charged float64
+		elapsed time.Duration
+		want    float64
+		wait    time.Duration
+	}{
+		{name: "full bucket", want: 1},
+		{name: "empty bucket", charged: 60, want: 1, wait: time.Second},
+		{name: "refilled while idle", charged: 60, elapsed: 10 * time.Second, want: 10},
+		{name: "in debt", charged: 90, want: 1, wait: 31 * time.Second},
+		{name: "larger than the bucket waits for a full one", charged: 30, want: 600, wait: 30 * time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+			b := newBucket(60, start)
+			b.charge(tt.charged, start)
+			if got := b.wait(tt.want, start.Add(tt.elapsed)); got != tt.wait {
+				t.Errorf("wait(%v) = %v, want %v", tt.want, got, tt.wait)
+			}
+		})
+	}
+}
+
+func TestAcquire(t *testing.T) {
+	type call struct {
+		tokens     int
+		stream     bool
+		release    bool
+		after      time.Duration
+		allowed    bool
+		reason     string
+		retryAfter time.Duration
+	}
+	tests := []struct {
+		name   string
+		limits keys.RateLimit
+		calls  []call
+	}{
+		{
+			name:   "requests per minute",
+			limits: keys.RateLimit{RequestsPerMinute: 2},
+			calls: []call{
+				{allowed: true},
+				{allowed: true},
+				{reason: "requests", retryAfter: 30 * time.Second},
+				{after: 30 * time.Second, allowed: true},
+			},
+		},
+		{
+			name:   "tokens per minute",
+			limits: keys.RateLimit{TokensPerMinute: 600},
+			calls: []call{
+				{tokens: 500, allowed: true},
+				{tokens: 200, reason: "tokens", retryAfter: 10 * time.Second},
+				{tokens: 200, after: 10 * time.Second, allowed: true},
+			},
+		},
+		{
+			name:   "concurrent streams",
+			limits: keys.RateLimit{MaxConcurrentStreams: 1},
+			calls: []call{
+				{stream: true, allowed: true},
+				{stream: true, reason: "streams", retryAfter: time.Second},
+				{allowed: true},
+				{release: true},
+				{stream: true, allowed: true},
+			},
+		},
+		{
+			name:  "no limits",
+			calls: []call{{tokens: 1 << 20, stream: true, allowed: true}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l, c := newTestLimiter()
+			for i, call := range tt.calls {
+				c.advance(call.after)
+				if call.release {
+					l.Release("key")
+					continue
+				}
+				res := l.Acquire("key", tt.limits, call.tokens, call.stream)
+				if res.Allowed != call.allowed || res.Reason != call.reason || res.RetryAfter != call.retryAfter {
+					t.Errorf("call %d: got allowed %v, reason %q, retry after %v, want %v, %q, %v",
+						i, res.Allowed, res.Reason, res.RetryAfter, call.allowed, call.reason, call.retryAfter)
+				}
+			}
+		})
+	}
+}
+
+func TestCharge(t *testing.T) {
+	tests := []struct {
+		name      string
+		estimate  int
+		charge    int
+		remaining int
+		reset     time.Duration
+	}{
+		{name: "exact estimate", estimate: 100, remaining: 500, reset: 10 * time.Second},
+		{name: "used more", estimate: 100, charge: 400, remaining: 100, reset: 50 * time.Second},
+		{name: "used less", estimate: 300, charge: -200, remaining: 500, reset: 10 * time.Second},
+		{name: "debt beyond the bucket", estimate: 600, charge: 300, remaining: 0, reset: 90 * time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l, _ := newTestLimiter()
+			limits := keys.RateLimit{TokensPerMinute: 600}
+			l.Acquire("key", limits, tt.estimate, false)
+			l.Charge("key", tt.charge)
+			res := l.Acquire("key", limits, 0, false)
+			if res.RemainingTokens != tt.remaining || res.ResetTokens != tt.reset {
+				t.Errorf("got remaining %d, reset %v, want %d, %v", res.RemainingTokens, res.ResetTokens, tt.remaining, tt.reset)
+			}
+		})
+	}
+}
+
+func TestIdleStatesAreDropped(t *testing.T) {
+	limits := keys.RateLimit{RequestsPerMinute: 60, TokensPerMinute: 600}
+	tests := []struct {
+		name   string
+		stream bool
+		tokens int
+		idle   time.Duration
+		kept   bool
+	}{
+		{name: "idle", idle: 2 * time.Minute},
+		{name: "recently used", idle: 30 * time.Second, kept: true},
+		{name: "open stream", stream: true, idle: 2 * time.Minute, kept: true},
+		{name: "still in debt", tokens: 2000, idle: 2 * time.Minute, kept: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l, c := newTestLimiter()
+			l.Acquire("ip:1", limits, 0, tt.stream)
+			l.Charge("ip:1", tt.tokens)
+			c.advance(tt.idle)
+			l.Acquire("ip:2", limits, 0, false)
+			if _, ok := l.states["ip:1"]; ok != tt.kept {
+				t.Errorf("state kept = %v, want %v", ok, tt.kept)
+			}
+		})
+	}
+}
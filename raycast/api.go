@@ -0,0 +1,61 @@
+package raycast
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+const (
+	ChatURL   = "https://backend.raycast.com/api/v1/ai/chat_completions"
+	ModelsURL = "https://backend.raycast.com/api/v1/ai/models"
+)
+
+// NewHTTPChatRequest builds the http request starting a completion.
+func NewHTTPChatRequest(ctx context.Context, token string, request ChatRequest) (*http.Request, error) {
+	rawReq, err := json.Marshal(request)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, ChatURL, bytes.NewReader(rawReq))
+	if err != nil {
+		return nil, err
+	}
+	SetHeaders(req, token)
+	return req, nil
+}
+
+// NewModelsRequest builds the http request listing the models of the
+// account.
+func NewModelsRequest(ctx context.Context, token string) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, ModelsURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	SetHeaders(req, token)
+	return req, nil
+}
+
+// SetHeaders makes req look like it comes from the raycast app.
+func SetHeaders(req *http.Request, token string) {
+	req.Header.Add("Accept", "application/json")
+	req.Header.Add("Accept-Language", "zh-CN,zh-Hans;q=0.9")
+	req.Header.Add("User-Agent", "Raycast/0 CFNetwork/1408.0.4 Darwin/22.5.0")
+	req.Header.Add("Content-Type", "application/json")
+	req.Header.Add("Authorization", "Bearer "+token)
+}
+
+// DecodeModels reads the answer to a models request and closes its body.
+func DecodeModels(res *http.Response) (ModelsResponse, error) {
+	resp := ModelsResponse{}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return resp, fmt.Errorf("get model info failed: %s", res.Status)
+	}
+	if err := json.NewDecoder(res.Body).Decode(&resp); err != nil {
+		return resp, fmt.Errorf("decode model info failed: %w", err)
+	}
+	return resp, nil
+}
@@ -0,0 +1,44 @@
+package raycast
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// ReadEvents calls fn for every event of a completion body.
+func ReadEvents(body io.Reader, fn func(StreamEvent) error) error {
+	scanner := bufio.NewScanner(body)
+	for scanner.Scan() {
+		event, ok, err := ParseEvent(scanner.Text())
+		if err != nil {
+			return err
+		}
+		if !ok {
+			continue
+		}
+		if err := fn(event); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+// ParseEvent decodes one line of a completion stream, ok is false for the
+// blank lines between events and sse comments. An error event or a line
+// that doesn't decode becomes an error.
+func ParseEvent(line string) (event StreamEvent, ok bool, err error) {
+	data := strings.TrimPrefix(line, "data: ")
+	if data == "" || strings.HasPrefix(data, ":") {
+		return event, false, nil
+	}
+	if err := json.Unmarshal([]byte(data), &event); err != nil {
+		return event, false, fmt.Errorf("decode raycast event: %w", err)
+	}
+	if event.Err != nil && event.Err != "" {
+		return event, false, fmt.Errorf("raycast error: %v", event.Err)
+	}
+	return event, true, nil
+}
@@ -0,0 +1,90 @@
+// Package raycast holds the wire types of the raycast AI api and the few
+// helpers needed to call it. It depends on nothing else in raychat, so the
+// server, the cli and the client library can all share it.
+package raycast
+
+type Content struct {
+	Text string `json:"text"`
+}
+
+type Message struct {
+	Content Content `json:"content"`
+	Author  string  `json:"author"`
+}
+
+type ChatRequest struct {
+	Debug                        bool      `json:"debug"`
+	Locale                       string    `json:"locale"`
+	Messages                     []Message `json:"messages"`
+	Source                       string    `json:"source"`
+	Provider                     string    `json:"provider"`
+	Model                        string    `json:"model"`
+	Temperature                  float64   `json:"temperature"`
+	SystemInstruction            string    `json:"system_instruction"`
+	AdditionalSystemInstructions string    `json:"additional_system_instructions,omitempty"`
+}
+
+// NewChatRequest builds a request the way the raycast app sends them,
+// system is optional.
+func NewChatRequest(model, provider string, messages []Message, system string) ChatRequest {
+	return ChatRequest{
+		Debug:                        false,
+		Locale:                       "en-CN",
+		Provider:                     provider,
+		Model:                        model,
+		Temperature:                  1,
+		SystemInstruction:            "markdown",
+		Messages:                     messages,
+		AdditionalSystemInstructions: system,
+	}
+}
+
+// StreamEvent is one event of a completion stream.
+type StreamEvent struct {
+	Text         string      `json:"text"`
+	Reasoning    string      `json:"reasoning"`
+	FinishReason *string     `json:"finish_reason"`
+	Err          interface{} `json:"error"`
+}
+
+type ModelsResponse struct {
+	Models        []ModelInfo `json:"models"`
+	DefaultModels struct {
+		Chat        string `json:"chat"`
+		QuickAi     string `json:"quick_ai"`
+		Commands    string `json:"commands"`
+		API         string `json:"api"`
+		EmojiSearch string `json:"emoji_search"`
+	} `json:"default_models"`
+}
+
+// SupportedModels maps every model to its provider.
+func (m ModelsResponse) SupportedModels() map[string]string {
+	models := map[string]string{}
+	for _, model := range m.Models {
+		models[model.Model] = model.Provider
+	}
+	return models
+}
+
+type ModelInfo struct {
+	ID                     string   `json:"id"`
+	Name                   string   `json:"name"`
+	Description            string   `json:"description"`
+	Status                 any      `json:"status"`
+	Features               []string `json:"features"`
+	Suggestions            []any    `json:"suggestions"`
+	InBetterAiSubscription bool     `json:"in_better_ai_subscription"`
+	Model                  string   `json:"model"`
+	Provider               string   `json:"provider"`
+	ProviderName           string   `json:"provider_name"`
+	ProviderBrand          string   `json:"provider_brand"`
+	Speed                  int      `json:"speed"`
+	Intelligence           float64  `json:"intelligence"`
+	RequiresBetterAi       bool     `json:"requires_better_ai"`
+	Context                int      `json:"context"`
+	Capabilities           struct {
+		WebSearch       string `json:"web_search,omitempty"`
+		ImageGeneration string `json:"image_generation,omitempty"`
+	} `json:"capabilities,omitempty"`
+}
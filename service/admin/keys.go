@@ -0,0 +1,108 @@
+package admin
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/Ken288yzs1/raychat/keys"
+	"github.com/Ken288yzs1/raychat/usage"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Service serves the admin api over the key store and the usage ledger.
+type Service struct {
+	keys   *keys.Store
+	ledger *usage.Ledger
+}
+
+func NewService(store *keys.Store, ledger *usage.Ledger) *Service {
+	return &Service{keys: store, ledger: ledger}
+}
+
+func (s *Service) ListKeysEndpoint(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"object": "list", "data": s.keys.List()})
+}
+
+func (s *Service) GetKeyEndpoint(c *gin.Context) {
+	k, err := s.keys.Get(c.Param("id"))
+	if err != nil {
+		keyError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, k)
+}
+
+func (s *Service) CreateKeyEndpoint(c *gin.Context) {
+	req := keys.CreateRequest{}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !validReasoningFormat(req.ReasoningFormat) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "reasoning_format must be field, think or hide"})
+		return
+	}
+	k, err := s.keys.Create(req)
+	if err != nil {
+		keyError(c, err)
+		return
+	}
+	keys.Logger().WithField("key_id", k.ID).Infof("api key %q created", k.Name)
+	c.JSON(http.StatusCreated, k)
+}
+
+func (s *Service) UpdateKeyEndpoint(c *gin.Context) {
+	req := keys.UpdateRequest{}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.ReasoningFormat != nil && !validReasoningFormat(*req.ReasoningFormat) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "reasoning_format must be field, think or hide"})
+		return
+	}
+	k, err := s.keys.Update(c.Param("id"), req)
+	if err != nil {
+		keyError(c, err)
+		return
+	}
+	keys.Logger().WithField("key_id", k.ID).Info("api key updated")
+	c.JSON(http.StatusOK, k)
+}
+
+func (s *Service) RotateKeyEndpoint(c *gin.Context) {
+	k, err := s.keys.Rotate(c.Param("id"))
+	if err != nil {
+		keyError(c, err)
+		return
+	}
+	keys.Logger().WithField("key_id", k.ID).Info("api key rotated")
+	c.JSON(http.StatusOK, k)
+}
+
+func (s *Service) RevokeKeyEndpoint(c *gin.Context) {
+	id := c.Param("id")
+	if err := s.keys.Revoke(id); err != nil {
+		keyError(c, err)
+		return
+	}
+	keys.Logger().WithField("key_id", id).Info("api key revoked")
+	c.JSON(http.StatusOK, gin.H{"id": id, "deleted": true})
+}
+
+// validReasoningFormat accepts an empty format, the key then follows the
+// server default.
+func validReasoningFormat(s string) bool {
+	_, ok := keys.ParseReasoningFormat(s)
+	return s == "" || ok
+}
+
+func keyError(c *gin.Context, err error) {
+	if errors.Is(err, keys.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	keys.Logger().WithError(err).Error("key store error")
+	c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+}
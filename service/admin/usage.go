@@ -0,0 +1,20 @@
+package admin
+
+import (
+	"net/http"
+
+	"github.com/Ken288yzs1/raychat/usage"
+
+	"github.com/gin-gonic/gin"
+)
+
+// UsageEndpoint reports usage across every key, key_id narrows it to one.
+func (s *Service) UsageEndpoint(c *gin.Context) {
+	f, err := usage.FilterFromQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	f.KeyID = c.Query("key_id")
+	s.ledger.WriteReport(c, f)
+}
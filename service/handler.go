@@ -1,30 +1,75 @@
 package service
 
 import (
-	"fmt"
-	"raychat/chat"
-	"raychat/middlewares"
-	"raychat/service/models"
-	"raychat/settings"
+	"strings"
+
+	"github.com/Ken288yzs1/raychat/logging"
+	"github.com/Ken288yzs1/raychat/metrics"
+	"github.com/Ken288yzs1/raychat/middlewares"
+	"github.com/Ken288yzs1/raychat/service/admin"
+	"github.com/Ken288yzs1/raychat/settings"
+	"github.com/Ken288yzs1/raychat/tracing"
 
 	"github.com/gin-gonic/gin"
 )
 
-func Run() {
-	r := gin.Default()
-	v1 := r.Group("/hf/v1")
+func (s *Server) router() *gin.Engine {
+	r := gin.New()
+	r.Use(gin.Recovery(), s.track, logging.RequestID, logging.AccessLog, tracing.Middleware)
+	if settings.Get().Metrics {
+		r.Use(metrics.Middleware)
+		r.GET("/metrics", metrics.Handler())
+	}
+	for _, prefix := range settings.Get().RoutePrefixes {
+		s.mount(r.Group(strings.TrimSpace(prefix), middlewares.CORS))
+	}
+	s.mountAdmin(r.Group("/admin", middlewares.Admin))
+
+	// probes stay unauthenticated and never reach raycast
+	r.GET("/healthz", s.chat.HealthzEndpoint)
+	r.GET("/readyz", s.chat.ReadyzEndpoint)
+	r.GET("/status", middlewares.Auth(s.keys), s.chat.StatusEndpoint)
+	return r
+}
+
+// mount registers the API under one prefix, every prefix gets the same
+// routes so /v1 and /hf/v1 behave identically.
+func (s *Server) mount(g *gin.RouterGroup) {
+	// preflight requests carry no Authorization header, keep them outside auth
+	g.OPTIONS("/models", OptionsHandler)
+	g.OPTIONS("/chat/completions", OptionsHandler)
+
+	api := g.Group("", middlewares.Auth(s.keys))
 	{
-		v1.GET("/models", models.GetModelsEndpoint)
-		v1.POST("/chat/completions", middlewares.Auth, chat.ChatEndpoint)
-		v1.OPTIONS("/chat/completions", OptionsHandler)
+		api.GET("/models", s.chat.ModelsEndpoint)
+		api.POST("/chat/completions", middlewares.RateLimit(s.limiter), s.chat.ChatEndpoint)
+		api.GET("/usage", s.ledger.UsageEndpoint)
 	}
-	r.Run(fmt.Sprintf(":%d", settings.Get().Port))
+}
+
+func (s *Server) mountAdmin(g *gin.RouterGroup) {
+	api := admin.NewService(s.keys, s.ledger)
+	g.GET("/keys", api.ListKeysEndpoint)
+	g.POST("/keys", api.CreateKeyEndpoint)
+	g.GET("/keys/:id", api.GetKeyEndpoint)
+	g.PATCH("/keys/:id", api.UpdateKeyEndpoint)
+	g.POST("/keys/:id/rotate", api.RotateKeyEndpoint)
+	g.DELETE("/keys/:id", api.RevokeKeyEndpoint)
+	g.GET("/usage", api.UsageEndpoint)
+}
+
+// track counts in-flight requests so shutdown can wait for them to finish
+// writing usage and transcripts.
+func (s *Server) track(c *gin.Context) {
+	s.inflight.Add(1)
+	defer s.inflight.Done()
+	c.Next()
 }
 
 func OptionsHandler(c *gin.Context) {
 	// Set headers for CORS
 	c.Header("Access-Control-Allow-Origin", "*")
-	c.Header("Access-Control-Allow-Methods", "POST")
+	c.Header("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
 	c.Header("Access-Control-Allow-Headers", "*")
 	c.JSON(200, gin.H{
 		"message": "pong",
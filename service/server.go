@@ -0,0 +1,155 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/Ken288yzs1/raychat/chat"
+	"github.com/Ken288yzs1/raychat/keys"
+	"github.com/Ken288yzs1/raychat/logging"
+	"github.com/Ken288yzs1/raychat/quota"
+	"github.com/Ken288yzs1/raychat/ratelimit"
+	"github.com/Ken288yzs1/raychat/settings"
+	"github.com/Ken288yzs1/raychat/tracing"
+	"github.com/Ken288yzs1/raychat/transcript"
+	"github.com/Ken288yzs1/raychat/usage"
+
+	"github.com/sirupsen/logrus"
+)
+
+func Logger() *logrus.Entry {
+	return logrus.WithField("prefix", "server")
+}
+
+// Server is the whole application. New opens its stores, nothing talks to
+// raycast or listens before Start.
+type Server struct {
+	chat *chat.Service
+	http *http.Server
+	errc chan error
+
+	keys    *keys.Store
+	ledger  *usage.Ledger
+	quotas  *quota.Tracker
+	limiter *ratelimit.Limiter
+	// transcripts is nil when TRANSCRIPT_DIR is unset
+	transcripts *transcript.Writer
+
+	inflight        sync.WaitGroup
+	shutdownTracing func(context.Context) error
+}
+
+// New builds the server for conf. conf also becomes the process wide
+// settings.Get, which config reloads replace.
+func New(conf settings.RayConfig) (*Server, error) {
+	settings.Set(conf)
+	logging.Setup(conf)
+	if len(conf.ExternalToken) == 0 {
+		logrus.Warn("ExternalToken is empty, skip auth, recommend to set it")
+	}
+
+	store, err := keys.Open(conf.KeysFile)
+	if err != nil {
+		return nil, fmt.Errorf("open keys file: %w", err)
+	}
+	ledger, err := usage.Open(conf.UsageFile, conf.UsageRetentionDays)
+	if err != nil {
+		return nil, fmt.Errorf("open usage file: %w", err)
+	}
+	transcripts, err := transcript.New(conf)
+	if err != nil {
+		ledger.Close()
+		return nil, err
+	}
+	quotas := quota.New(ledger)
+
+	s := &Server{
+		chat:        chat.NewService(ledger, quotas, transcripts),
+		errc:        make(chan error, 1),
+		keys:        store,
+		ledger:      ledger,
+		quotas:      quotas,
+		limiter:     ratelimit.New(),
+		transcripts: transcripts,
+	}
+	s.http = &http.Server{
+		Addr:              fmt.Sprintf(":%d", conf.Port),
+		ReadHeaderTimeout: 30 * time.Second,
+	}
+	settings.OnReload(func(old, conf settings.RayConfig) {
+		logging.Setup(conf)
+		s.chat.Reload(old, conf)
+	})
+	return s, nil
+}
+
+// Start logs into raycast and starts listening. It returns once the server
+// accepts connections, serve errors show up on Err.
+func (s *Server) Start(ctx context.Context) error {
+	shutdownTracing, err := tracing.Init(ctx)
+	if err != nil {
+		return fmt.Errorf("init tracing: %w", err)
+	}
+	s.shutdownTracing = shutdownTracing
+	if err := s.chat.Start(ctx); err != nil {
+		return err
+	}
+
+	go settings.Watch(ctx)
+
+	s.http.Handler = s.router()
+	ln, err := net.Listen("tcp", s.http.Addr)
+	if err != nil {
+		return err
+	}
+	Logger().Infof("listening on %s", ln.Addr())
+	go func() {
+		if err := s.http.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			s.errc <- err
+		}
+	}()
+	return nil
+}
+
+// Err reports the server failing after Start.
+func (s *Server) Err() <-chan error {
+	return s.errc
+}
+
+// Shutdown stops accepting connections and waits for in-flight requests,
+// streams included, until ctx expires. Whatever is left then is cut off.
+func (s *Server) Shutdown(ctx context.Context) error {
+	Logger().Info("shutting down, draining in-flight requests")
+	err := s.http.Shutdown(ctx)
+	if err != nil {
+		Logger().WithError(err).Warn("drain timed out, closing remaining connections")
+		s.http.Close()
+	}
+
+	// closed connections cancel their requests, give the handlers a moment
+	// to record what they did
+	done := make(chan struct{})
+	go func() {
+		s.inflight.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		Logger().Warn("handlers still running after close")
+	}
+
+	if s.transcripts != nil {
+		s.transcripts.Close()
+	}
+	s.ledger.Close()
+	if s.shutdownTracing != nil {
+		s.shutdownTracing(context.Background())
+	}
+	return err
+}
@@ -0,0 +1,169 @@
+package settings
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// credentials is what an encrypted CREDENTIALS_FILE holds, as yaml or json.
+type credentials struct {
+	ClientID      string   `yaml:"client_id"`
+	ClientSecret  string   `yaml:"client_secret"`
+	Email         string   `yaml:"email"`
+	Password      string   `yaml:"password"`
+	Token         string   `yaml:"token"`
+	ExternalToken []string `yaml:"external_token"`
+	AdminToken    string   `yaml:"admin_token"`
+}
+
+// loadCredentials fills the credential fields from their *_FILE variants and
+// the encrypted credentials file. A value set directly wins over the
+// *_FILE, which wins over the encrypted file.
+func (c *RayConfig) loadCredentials() error {
+	var creds credentials
+	if c.CredentialsFile != "" {
+		var err error
+		if creds, err = readCredentialsFile(c.CredentialsFile, c.CredentialsKey); err != nil {
+			return err
+		}
+	}
+
+	secrets := []struct {
+		name  string
+		value *string
+		path  string
+		enc   string
+	}{
+		{"CLIENT_ID", &c.ClientID, c.ClientIDFile, creds.ClientID},
+		{"CLIENT_SECRET", &c.ClientSecret, c.ClientSecretFile, creds.ClientSecret},
+		{"EMAIL", &c.Email, c.EmailFile, creds.Email},
+		{"PASSWORD", &c.Password, c.PasswordFile, creds.Password},
+		{"TOKEN", &c.Token, c.TokenFile, creds.Token},
+		{"ADMIN_TOKEN", &c.AdminToken, c.AdminTokenFile, creds.AdminToken},
+	}
+	for _, s := range secrets {
+		if *s.value != "" {
+			continue
+		}
+		if s.path != "" {
+			v, err := readSecretFile(s.name, s.path)
+			if err != nil {
+				return err
+			}
+			*s.value = v
+			continue
+		}
+		*s.value = s.enc
+	}
+
+	for i, p := range c.Providers {
+		if p.APIKey == "" && p.APIKeyFile != "" {
+			data, err := os.ReadFile(p.APIKeyFile)
+			if err != nil {
+				return fmt.Errorf("read api_key_file of provider %s: %w", p.Name, err)
+			}
+			c.Providers[i].APIKey = strings.TrimSpace(string(data))
+		}
+	}
+
+	if len(c.ExternalToken) == 0 {
+		switch {
+		case c.ExternalTokenFile != "":
+			v, err := readSecretFile("EXTERNAL_TOKEN", c.ExternalTokenFile)
+			if err != nil {
+				return err
+			}
+			// one token per line or comma separated, like the env var
+			c.ExternalToken = strings.FieldsFunc(v, func(r rune) bool {
+				return r == ',' || r == '\n' || r == '\r'
+			})
+		default:
+			c.ExternalToken = creds.ExternalToken
+		}
+	}
+	return nil
+}
+
+func readSecretFile(name, path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("read %s_FILE: %w", name, err)
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+func readCredentialsFile(path, key string) (credentials, error) {
+	var creds credentials
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return creds, fmt.Errorf("read credentials file: %w", err)
+	}
+	plain, err := DecryptCredentials(data, key)
+	if err != nil {
+		return creds, fmt.Errorf("decrypt credentials file %s: %w", path, err)
+	}
+	if err := yaml.Unmarshal(plain, &creds); err != nil {
+		return creds, fmt.Errorf("parse credentials file %s: %w", path, err)
+	}
+	return creds, nil
+}
+
+// EncryptCredentials seals plain with AES-256-GCM under key, a base64
+// encoded 32 byte key. The result is base64 text so it survives being put
+// in a kubernetes secret or env var.
+func EncryptCredentials(plain []byte, key string) ([]byte, error) {
+	aead, err := credentialsCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	sealed := aead.Seal(nonce, nonce, plain, nil)
+	return []byte(base64.StdEncoding.EncodeToString(sealed) + "\n"), nil
+}
+
+// DecryptCredentials reverses EncryptCredentials.
+func DecryptCredentials(data []byte, key string) ([]byte, error) {
+	aead, err := credentialsCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	sealed, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(data)))
+	if err != nil {
+		return nil, fmt.Errorf("decode: %w", err)
+	}
+	if len(sealed) < aead.NonceSize() {
+		return nil, errors.New("file is too short")
+	}
+	nonce, sealed := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	plain, err := aead.Open(nil, nonce, sealed, nil)
+	if err != nil {
+		return nil, errors.New("wrong key or corrupted file")
+	}
+	return plain, nil
+}
+
+func credentialsCipher(key string) (cipher.AEAD, error) {
+	if key == "" {
+		return nil, errors.New("CREDENTIALS_KEY is not set")
+	}
+	raw, err := base64.StdEncoding.DecodeString(key)
+	if err != nil || len(raw) != 32 {
+		return nil, errors.New("CREDENTIALS_KEY must be 32 bytes, base64 encoded")
+	}
+	block, err := aes.NewCipher(raw)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,99 @@
+package settings
+
+import (
+	"bytes"
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func newCredentialsKey(b byte) string {
+	return base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{b}, 32))
+}
+
+func TestCredentialsRoundTrip(t *testing.T) {
+	key := newCredentialsKey(1)
+	plain := []byte("email: a@b.c\npassword: secret\n")
+	sealed, err := EncryptCredentials(plain, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(sealed, []byte("secret")) {
+		t.Fatal("sealed file holds the password")
+	}
+	again, err := EncryptCredentials(plain, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Equal(sealed, again) {
+		t.Error("two encryptions share a nonce")
+	}
+	got, err := DecryptCredentials(sealed, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, plain) {
+		t.Errorf("decrypted %q, want %q", got, plain)
+	}
+}
+
+func TestDecryptCredentialsErrors(t *testing.T) {
+	key := newCredentialsKey(1)
+	sealed, err := EncryptCredentials([]byte("token: t\n"), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw, _ := base64.StdEncoding.DecodeString(strings.TrimSpace(string(sealed)))
+	raw[len(raw)-1] ^= 1
+	tampered := []byte(base64.StdEncoding.EncodeToString(raw))
+
+	tests := []struct {
+		name    string
+		data    []byte
+		key     string
+		wantErr string
+	}{
+		{name: "wrong key", data: sealed, key: newCredentialsKey(2), wantErr: "wrong key or corrupted file"},
+		{name: "tampered", data: tampered, key: key, wantErr: "wrong key or corrupted file"},
+		{name: "not base64", data: []byte("???"), key: key, wantErr: "decode"},
+		{name: "too short", data: []byte(base64.StdEncoding.EncodeToString([]byte("abc"))), key: key, wantErr: "too short"},
+		{name: "no key", data: sealed, wantErr: "CREDENTIALS_KEY is not set"},
+		{name: "short key", data: sealed, key: base64.StdEncoding.EncodeToString([]byte("short")), wantErr: "must be 32 bytes"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := DecryptCredentials(tt.data, tt.key)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("err = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoadCredentialsFile(t *testing.T) {
+	key := newCredentialsKey(1)
+	sealed, err := EncryptCredentials([]byte("email: a@b.c\npassword: secret\nexternal_token: [t1, t2]\n"), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "credentials.enc")
+	if err := os.WriteFile(path, sealed, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	// a value set directly wins over the file
+	c := RayConfig{CredentialsFile: path, CredentialsKey: key, Email: "env@b.c"}
+	if err := c.loadCredentials(); err != nil {
+		t.Fatal(err)
+	}
+	if c.Email != "env@b.c" || c.Password != "secret" || len(c.ExternalToken) != 2 {
+		t.Errorf("got email %q, password %q, external tokens %v", c.Email, c.Password, c.ExternalToken)
+	}
+
+	c = RayConfig{CredentialsFile: path, CredentialsKey: newCredentialsKey(2)}
+	if err := c.loadCredentials(); err == nil {
+		t.Error("loaded with the wrong key")
+	}
+}
@@ -0,0 +1,140 @@
+package settings
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"reflect"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/samber/lo"
+	"github.com/sirupsen/logrus"
+)
+
+// secrets never show up in the reload diff
+var secretFields = []string{"ClientSecret", "Password", "Token", "ExternalToken", "AdminToken", "CredentialsKey", "QuotaWebhookURL", "Providers"}
+
+// these are only read at startup, changing them needs a restart
+var restartFields = []string{
+	"Port", "RoutePrefixes", "Metrics",
+	"KeysFile", "UsageFile", "UsageRetentionDays", "ConfigWatchInterval",
+	"UpstreamProxy", "UpstreamConnectTimeout", "UpstreamFirstByteTimeout", "UpstreamIdleTimeout", "UpstreamMaxIdleConns",
+	"TracingExporter", "TracingEndpoint", "TracingServiceName", "TracingSampleRatio",
+	"TranscriptDir", "TranscriptMaxSizeMB", "TranscriptMaxAge", "TranscriptRetention",
+	"UpstreamMode", "FixturesDir", "Providers", "ModelsRefreshInterval",
+}
+
+var (
+	reloadMu  sync.Mutex
+	listeners []func(old, conf RayConfig)
+)
+
+func Logger() *logrus.Entry {
+	return logrus.WithField("prefix", "settings")
+}
+
+// OnReload registers fn to run after a new config has been installed.
+func OnReload(fn func(old, conf RayConfig)) {
+	reloadMu.Lock()
+	defer reloadMu.Unlock()
+	listeners = append(listeners, fn)
+}
+
+// Reload reads the config again and swaps it in when it is valid, a bad
+// config keeps the current one running.
+func Reload() error {
+	reloadMu.Lock()
+	defer reloadMu.Unlock()
+
+	conf, err := Load()
+	if err != nil {
+		return err
+	}
+	old := Get()
+	changed := Diff(old, conf)
+	if len(changed) == 0 {
+		Logger().Info("config reloaded, nothing changed")
+		return nil
+	}
+	Set(conf)
+	for _, field := range changed {
+		entry := Logger().WithField("field", field)
+		if !lo.Contains(secretFields, field) {
+			entry = entry.WithFields(logrus.Fields{
+				"old": fmt.Sprint(reflect.ValueOf(old).FieldByName(field)),
+				"new": fmt.Sprint(reflect.ValueOf(conf).FieldByName(field)),
+			})
+		}
+		if lo.Contains(restartFields, field) {
+			entry.Warn("config changed, takes effect after a restart")
+		} else {
+			entry.Info("config changed")
+		}
+	}
+	for _, fn := range listeners {
+		fn(old, conf)
+	}
+	return nil
+}
+
+// Diff lists the names of the fields that differ between a and b.
+func Diff(a, b RayConfig) []string {
+	var changed []string
+	va, vb := reflect.ValueOf(a), reflect.ValueOf(b)
+	for i := 0; i < va.NumField(); i++ {
+		if !reflect.DeepEqual(va.Field(i).Interface(), vb.Field(i).Interface()) {
+			changed = append(changed, va.Type().Field(i).Name)
+		}
+	}
+	return changed
+}
+
+// Watch reloads the config on SIGHUP and whenever the config file changes,
+// until ctx is done.
+func Watch(ctx context.Context) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	conf := Get()
+	var poll <-chan time.Time
+	if conf.ConfigFile != "" && conf.ConfigWatchInterval > 0 {
+		ticker := time.NewTicker(conf.ConfigWatchInterval)
+		defer ticker.Stop()
+		poll = ticker.C
+	}
+	lastMod := modTime(conf.ConfigFile)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hup:
+			Logger().Info("SIGHUP received, reloading config")
+		case <-poll:
+			mod := modTime(conf.ConfigFile)
+			if mod.Equal(lastMod) {
+				continue
+			}
+			lastMod = mod
+			Logger().Infof("%s changed, reloading config", conf.ConfigFile)
+		}
+		if err := Reload(); err != nil {
+			Logger().WithError(err).Error("reload config failed, keep the current one")
+		}
+	}
+}
+
+func modTime(path string) time.Time {
+	if path == "" {
+		return time.Time{}
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return time.Time{}
+	}
+	return info.ModTime()
+}
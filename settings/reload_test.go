@@ -0,0 +1,119 @@
+package settings
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/Ken288yzs1/raychat/keys"
+)
+
+func validConfig() RayConfig {
+	return RayConfig{Port: 7860, LogFormat: "json", LogLevel: "info", ReasoningFormat: "field", UpstreamMode: "live"}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		change  func(c *RayConfig)
+		wantErr string
+	}{
+		{name: "valid", change: func(c *RayConfig) {}},
+		{name: "reasoning format any case", change: func(c *RayConfig) { c.ReasoningFormat = "Think" }},
+		{name: "bad port", change: func(c *RayConfig) { c.Port = 70000 }, wantErr: "port 70000 out of range"},
+		{name: "bad reasoning format", change: func(c *RayConfig) { c.ReasoningFormat = "inline" }, wantErr: "reasoning_format must be field, think or hide"},
+		{name: "bad key reasoning format", change: func(c *RayConfig) {
+			c.Keys = map[string]keys.Overrides{"ci": {ReasoningFormat: "inline"}}
+		}, wantErr: "keys.ci: reasoning_format"},
+		{name: "bad key quota period", change: func(c *RayConfig) {
+			c.Keys = map[string]keys.Overrides{"ci": {Quotas: []keys.Quota{{Period: "week"}}}}
+		}, wantErr: "keys.ci: quotas[0]: period"},
+		{name: "short retention", change: func(c *RayConfig) { c.UsageRetentionDays = 7 }, wantErr: "usage_retention_days"},
+		{name: "unknown route provider", change: func(c *RayConfig) { c.ModelRoutes = map[string]string{"llama-*": "vllm"} }, wantErr: `unknown provider "vllm"`},
+		{name: "short fallback", change: func(c *RayConfig) { c.ModelFallbacks = []string{"gpt-4o"} }, wantErr: "needs at least two models"},
+		{name: "second chain", change: func(c *RayConfig) { c.ModelFallbacks = []string{"a -> b", "a -> c"} }, wantErr: "a already has a chain"},
+		{name: "every error reported", change: func(c *RayConfig) { c.Port = 0; c.LogFormat = "xml" }, wantErr: "port 0 out of range\nlog_format must be json or text"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := validConfig()
+			tt.change(&c)
+			err := c.Validate()
+			if tt.wantErr == "" && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Fatalf("err = %v, want one containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestDiff(t *testing.T) {
+	a := validConfig()
+	b := a
+	if changed := Diff(a, b); len(changed) != 0 {
+		t.Errorf("equal configs differ in %v", changed)
+	}
+	b.LogLevel = "debug"
+	b.ExternalToken = []string{"t1"}
+	b.Keys = map[string]keys.Overrides{"ci": {AllowedModels: []string{"gpt-4o"}}}
+	want := []string{"LogLevel", "ExternalToken", "Keys"}
+	changed := Diff(a, b)
+	slices.Sort(changed)
+	slices.Sort(want)
+	if !slices.Equal(changed, want) {
+		t.Errorf("Diff = %v, want %v", changed, want)
+	}
+}
+
+func TestReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	write := func(content string) {
+		t.Helper()
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Setenv("CONFIG_FILE", path)
+	write("log_level: info\nreasoning_format: field\n")
+	conf, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	old := Get()
+	Set(conf)
+	defer Set(old)
+
+	var calls [][2]RayConfig
+	OnReload(func(old, conf RayConfig) { calls = append(calls, [2]RayConfig{old, conf}) })
+
+	if err := Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if len(calls) != 0 {
+		t.Fatal("listeners ran without a change")
+	}
+
+	write("log_level: debug\nreasoning_format: think\n")
+	if err := Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if got := Get(); got.LogLevel != "debug" || got.ReasoningFormat != "think" {
+		t.Errorf("after reload log_level %q, reasoning_format %q", got.LogLevel, got.ReasoningFormat)
+	}
+	if len(calls) != 1 || calls[0][0].LogLevel != "info" || calls[0][1].LogLevel != "debug" {
+		t.Fatalf("listener calls %+v", calls)
+	}
+
+	// a broken config keeps the current one
+	write("log_level: debug\nreasoning_format: inline\n")
+	if err := Reload(); err == nil {
+		t.Error("invalid config reloaded")
+	}
+	if got := Get(); got.ReasoningFormat != "think" || len(calls) != 1 {
+		t.Errorf("invalid config installed: reasoning_format %q, %d listener calls", got.ReasoningFormat, len(calls))
+	}
+}
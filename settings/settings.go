@@ -1,36 +1,262 @@
 package settings
 
 import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Ken288yzs1/raychat/keys"
+
 	"github.com/ilyakaznacheev/cleanenv"
 	"github.com/joho/godotenv"
+	"github.com/samber/lo"
 	"github.com/sirupsen/logrus"
 )
 
 type RayConfig struct {
-	ClientID      string   `env:"CLIENT_ID"`
-	ClientSecret  string   `env:"CLIENT_SECRET"`
-	Email         string   `env:"EMAIL"`
-	Password      string   `env:"PASSWORD"`
-	Token         string   `env:"TOKEN" env-default:""`
-	ExternalToken []string `env:"EXTERNAL_TOKEN" env-default:""`
-	Port          int      `env:"PORT" env-default:"7860"`
+	// ConfigFile is an optional yaml or toml file, env vars win over it
+	ConfigFile          string        `env:"CONFIG_FILE" env-default:"" yaml:"-" toml:"-"`
+	ConfigWatchInterval time.Duration `env:"CONFIG_WATCH_INTERVAL" env-default:"5s" yaml:"config_watch_interval" toml:"config_watch_interval"`
+
+	ClientID      string   `env:"CLIENT_ID" yaml:"client_id" toml:"client_id"`
+	ClientSecret  string   `env:"CLIENT_SECRET" yaml:"client_secret" toml:"client_secret"`
+	Email         string   `env:"EMAIL" yaml:"email" toml:"email"`
+	Password      string   `env:"PASSWORD" yaml:"password" toml:"password"`
+	Token         string   `env:"TOKEN" env-default:"" yaml:"token" toml:"token"`
+	ExternalToken []string `env:"EXTERNAL_TOKEN" env-default:"" yaml:"external_token" toml:"external_token"`
+	// the *_FILE variants read a credential from a file, e.g. a docker or
+	// kubernetes secret, when it isn't set directly
+	ClientIDFile      string `env:"CLIENT_ID_FILE" env-default:"" yaml:"client_id_file" toml:"client_id_file"`
+	ClientSecretFile  string `env:"CLIENT_SECRET_FILE" env-default:"" yaml:"client_secret_file" toml:"client_secret_file"`
+	EmailFile         string `env:"EMAIL_FILE" env-default:"" yaml:"email_file" toml:"email_file"`
+	PasswordFile      string `env:"PASSWORD_FILE" env-default:"" yaml:"password_file" toml:"password_file"`
+	TokenFile         string `env:"TOKEN_FILE" env-default:"" yaml:"token_file" toml:"token_file"`
+	ExternalTokenFile string `env:"EXTERNAL_TOKEN_FILE" env-default:"" yaml:"external_token_file" toml:"external_token_file"`
+	AdminTokenFile    string `env:"ADMIN_TOKEN_FILE" env-default:"" yaml:"admin_token_file" toml:"admin_token_file"`
+	// CredentialsFile is an encrypted file with any of the credentials above,
+	// sealed with CredentialsKey
+	CredentialsFile string `env:"CREDENTIALS_FILE" env-default:"" yaml:"credentials_file" toml:"credentials_file"`
+	CredentialsKey  string `env:"CREDENTIALS_KEY" env-default:"" yaml:"-" toml:"-"`
+	Port            int    `env:"PORT" env-default:"7860" yaml:"port" toml:"port"`
+	// ShutdownTimeout is how long in-flight streams get to finish on SIGTERM
+	ShutdownTimeout time.Duration `env:"SHUTDOWN_TIMEOUT" env-default:"30s" yaml:"shutdown_timeout" toml:"shutdown_timeout"`
+	Debug           bool          `env:"DEBUG" env-default:"false" yaml:"debug" toml:"debug"`
+	LogFormat       string        `env:"LOG_FORMAT" env-default:"json" yaml:"log_format" toml:"log_format"`
+	LogLevel        string        `env:"LOG_LEVEL" env-default:"info" yaml:"log_level" toml:"log_level"`
+	// LogRedactContent also masks prompts and completions in logs
+	LogRedactContent bool     `env:"LOG_REDACT_CONTENT" env-default:"false" yaml:"log_redact_content" toml:"log_redact_content"`
+	RoutePrefixes    []string `env:"ROUTE_PREFIXES" env-default:"/v1,/hf/v1" yaml:"route_prefixes" toml:"route_prefixes"`
+	AdminToken       string   `env:"ADMIN_TOKEN" env-default:"" yaml:"admin_token" toml:"admin_token"`
+	KeysFile         string   `env:"KEYS_FILE" env-default:"" yaml:"keys_file" toml:"keys_file"`
+	UsageFile        string   `env:"USAGE_FILE" env-default:"" yaml:"usage_file" toml:"usage_file"`
+	// UsageRetentionDays is how many days of usage are kept, 0 keeps all
+	UsageRetentionDays int  `env:"USAGE_RETENTION_DAYS" env-default:"400" yaml:"usage_retention_days" toml:"usage_retention_days"`
+	Metrics            bool `env:"METRICS" env-default:"true" yaml:"metrics" toml:"metrics"`
+
+	ReasoningFormat string `env:"REASONING_FORMAT" env-default:"field" yaml:"reasoning_format" toml:"reasoning_format"`
+
+	UpstreamProxy            string        `env:"UPSTREAM_PROXY" env-default:"" yaml:"upstream_proxy" toml:"upstream_proxy"`
+	UpstreamConnectTimeout   time.Duration `env:"UPSTREAM_CONNECT_TIMEOUT" env-default:"10s" yaml:"upstream_connect_timeout" toml:"upstream_connect_timeout"`
+	UpstreamFirstByteTimeout time.Duration `env:"UPSTREAM_FIRST_BYTE_TIMEOUT" env-default:"2m" yaml:"upstream_first_byte_timeout" toml:"upstream_first_byte_timeout"`
+	UpstreamIdleTimeout      time.Duration `env:"UPSTREAM_IDLE_TIMEOUT" env-default:"90s" yaml:"upstream_idle_timeout" toml:"upstream_idle_timeout"`
+	UpstreamMaxIdleConns     int           `env:"UPSTREAM_MAX_IDLE_CONNS" env-default:"32" yaml:"upstream_max_idle_conns" toml:"upstream_max_idle_conns"`
+
+	RateLimitRPM     int `env:"RATE_LIMIT_RPM" env-default:"0" yaml:"rate_limit_rpm" toml:"rate_limit_rpm"`
+	RateLimitTPM     int `env:"RATE_LIMIT_TPM" env-default:"0" yaml:"rate_limit_tpm" toml:"rate_limit_tpm"`
+	RateLimitStreams int `env:"RATE_LIMIT_STREAMS" env-default:"0" yaml:"rate_limit_streams" toml:"rate_limit_streams"`
+
+	QuotaWarnRatio  float64 `env:"QUOTA_WARN_RATIO" env-default:"0.8" yaml:"quota_warn_ratio" toml:"quota_warn_ratio"`
+	QuotaWebhookURL string  `env:"QUOTA_WEBHOOK_URL" env-default:"" yaml:"quota_webhook_url" toml:"quota_webhook_url"`
+
+	TracingExporter    string  `env:"TRACING_EXPORTER" env-default:"" yaml:"tracing_exporter" toml:"tracing_exporter"`
+	TracingEndpoint    string  `env:"TRACING_ENDPOINT" env-default:"" yaml:"tracing_endpoint" toml:"tracing_endpoint"`
+	TracingServiceName string  `env:"TRACING_SERVICE_NAME" env-default:"raychat" yaml:"tracing_service_name" toml:"tracing_service_name"`
+	TracingSampleRatio float64 `env:"TRACING_SAMPLE_RATIO" env-default:"1" yaml:"tracing_sample_ratio" toml:"tracing_sample_ratio"`
+
+	TranscriptDir       string        `env:"TRANSCRIPT_DIR" env-default:"" yaml:"transcript_dir" toml:"transcript_dir"`
+	TranscriptMaxSizeMB int           `env:"TRANSCRIPT_MAX_SIZE_MB" env-default:"100" yaml:"transcript_max_size_mb" toml:"transcript_max_size_mb"`
+	TranscriptMaxAge    time.Duration `env:"TRANSCRIPT_MAX_AGE" env-default:"24h" yaml:"transcript_max_age" toml:"transcript_max_age"`
+	TranscriptRetention time.Duration `env:"TRANSCRIPT_RETENTION" env-default:"720h" yaml:"transcript_retention" toml:"transcript_retention"`
+
+	// UpstreamMode is live, record (save raycast responses to FixturesDir) or
+	// replay (answer from FixturesDir without contacting raycast)
+	UpstreamMode string `env:"UPSTREAM_MODE" env-default:"live" yaml:"upstream_mode" toml:"upstream_mode"`
+	FixturesDir  string `env:"FIXTURES_DIR" env-default:"testdata/fixtures" yaml:"fixtures_dir" toml:"fixtures_dir"`
+
+	StreamHeartbeat        time.Duration `env:"STREAM_HEARTBEAT" env-default:"15s" yaml:"stream_heartbeat" toml:"stream_heartbeat"`
+	StreamFirstByteTimeout time.Duration `env:"STREAM_FIRST_BYTE_TIMEOUT" env-default:"0" yaml:"stream_first_byte_timeout" toml:"stream_first_byte_timeout"`
+	StreamIdleTimeout      time.Duration `env:"STREAM_IDLE_TIMEOUT" env-default:"0" yaml:"stream_idle_timeout" toml:"stream_idle_timeout"`
+
+	// ModelAliases maps names clients send to raycast models, e.g. gpt-4:openai-gpt-4o
+	ModelAliases map[string]string `env:"MODEL_ALIASES" env-default:"" yaml:"model_aliases" toml:"model_aliases"`
+	// Keys overrides settings of api keys by id or name, config file only
+	Keys map[string]keys.Overrides `yaml:"keys" toml:"keys"`
+
+	// Providers are openai compatible backends served next to raycast,
+	// config file only
+	Providers []Provider `yaml:"providers" toml:"providers"`
+	// ModelRoutes sends models to a provider by name or trailing * pattern,
+	// e.g. llama-*:vllm, models a provider lists are routed to it anyway
+	ModelRoutes map[string]string `env:"MODEL_ROUTES" env-default:"" yaml:"model_routes" toml:"model_routes"`
+	// ModelsRefreshInterval is how often the catalog of every upstream is
+	// loaded again, 0 only loads it at start and on credential changes
+	ModelsRefreshInterval time.Duration `env:"MODELS_REFRESH_INTERVAL" env-default:"1h" yaml:"models_refresh_interval" toml:"models_refresh_interval"`
+	// ModelFallbacks are chains like "claude-opus -> claude-sonnet -> gpt-4o",
+	// a failing model is retried with the next one
+	ModelFallbacks []string `env:"MODEL_FALLBACKS" env-default:"" yaml:"model_fallbacks" toml:"model_fallbacks"`
 }
 
-var rayConf RayConfig
+type Provider struct {
+	Name string `yaml:"name" toml:"name"`
+	// Type is the api the provider speaks, only openai for now
+	Type       string `yaml:"type" toml:"type"`
+	BaseURL    string `yaml:"base_url" toml:"base_url"`
+	APIKey     string `yaml:"api_key" toml:"api_key"`
+	APIKeyFile string `yaml:"api_key_file" toml:"api_key_file"`
+	// Models are served by the provider, fetched from its /models when empty
+	Models []string `yaml:"models" toml:"models"`
+}
+
+var (
+	current    atomic.Pointer[RayConfig]
+	dotenvOnce sync.Once
+)
 
-func init() {
-	if err := godotenv.Load(); err != nil {
-		logrus.WithError(err).Warn("load .env file error, try to read from env")
+// Load reads the config from CONFIG_FILE when set and the environment, a
+// .env file is picked up when present.
+func Load() (RayConfig, error) {
+	var conf RayConfig
+	dotenvOnce.Do(func() {
+		if err := godotenv.Load(); err != nil {
+			logrus.WithError(err).Warn("load .env file error, try to read from env")
+		}
+	})
+	if path := os.Getenv("CONFIG_FILE"); path != "" {
+		if err := cleanenv.ReadConfig(path, &conf); err != nil {
+			return conf, fmt.Errorf("read config file %s: %w", path, err)
+		}
+	} else if err := cleanenv.ReadEnv(&conf); err != nil {
+		return conf, fmt.Errorf("read env: %w", err)
 	}
-	err := cleanenv.ReadEnv(&rayConf)
-	if err != nil {
-		logrus.Panic("read env error", err)
+	if err := conf.loadCredentials(); err != nil {
+		return conf, err
 	}
-	if len(rayConf.ExternalToken) == 0 {
-		logrus.Warn("ExternalToken is empty, skip auth, recommend to set it")
+	if err := conf.Validate(); err != nil {
+		return conf, err
 	}
+	return conf, nil
 }
 
+// Set installs conf as the config returned by Get.
+func Set(conf RayConfig) {
+	current.Store(&conf)
+}
+
+// Get returns the current config, it may be swapped by a reload at any time
+// so read it once per request rather than caching it.
 func Get() RayConfig {
-	return rayConf
+	if conf := current.Load(); conf != nil {
+		return *conf
+	}
+	return RayConfig{}
+}
+
+// Validate rejects configs that would only fail later at runtime.
+func (c RayConfig) Validate() error {
+	var errs []error
+	if c.Port <= 0 || c.Port > 65535 {
+		errs = append(errs, fmt.Errorf("port %d out of range", c.Port))
+	}
+	if c.LogFormat != "json" && c.LogFormat != "text" {
+		errs = append(errs, fmt.Errorf("log_format must be json or text, got %q", c.LogFormat))
+	}
+	if _, err := logrus.ParseLevel(c.LogLevel); err != nil {
+		errs = append(errs, fmt.Errorf("log_level: %w", err))
+	}
+	if _, ok := keys.ParseReasoningFormat(c.ReasoningFormat); !ok {
+		errs = append(errs, fmt.Errorf("reasoning_format must be field, think or hide, got %q", c.ReasoningFormat))
+	}
+	if !lo.Contains([]string{"live", "record", "replay"}, c.UpstreamMode) {
+		errs = append(errs, fmt.Errorf("upstream_mode must be live, record or replay, got %q", c.UpstreamMode))
+	}
+	if c.UsageRetentionDays != 0 && c.UsageRetentionDays < 31 {
+		// monthly quotas need the whole month
+		errs = append(errs, fmt.Errorf("usage_retention_days must be 0 or at least 31, got %d", c.UsageRetentionDays))
+	}
+	if c.ModelsRefreshInterval < 0 {
+		errs = append(errs, fmt.Errorf("models_refresh_interval must not be negative"))
+	}
+	if c.QuotaWarnRatio < 0 || c.QuotaWarnRatio > 1 {
+		errs = append(errs, fmt.Errorf("quota_warn_ratio must be within 0 and 1"))
+	}
+	if c.TracingSampleRatio < 0 || c.TracingSampleRatio > 1 {
+		errs = append(errs, fmt.Errorf("tracing_sample_ratio must be within 0 and 1"))
+	}
+	for alias, model := range c.ModelAliases {
+		if alias == "" || model == "" {
+			errs = append(errs, fmt.Errorf("model alias %q:%q is incomplete", alias, model))
+		}
+	}
+	providers := map[string]bool{"raycast": true}
+	for i, p := range c.Providers {
+		switch {
+		case p.Name == "":
+			errs = append(errs, fmt.Errorf("providers[%d]: name is required", i))
+		case providers[p.Name]:
+			errs = append(errs, fmt.Errorf("providers[%d]: name %q is taken", i, p.Name))
+		case p.Type != "openai":
+			errs = append(errs, fmt.Errorf("providers.%s: type must be openai, got %q", p.Name, p.Type))
+		case p.BaseURL == "":
+			errs = append(errs, fmt.Errorf("providers.%s: base_url is required", p.Name))
+		}
+		providers[p.Name] = true
+	}
+	for pattern, provider := range c.ModelRoutes {
+		if !providers[provider] {
+			errs = append(errs, fmt.Errorf("model route %s: unknown provider %q", pattern, provider))
+		}
+	}
+	heads := map[string]bool{}
+	for _, chain := range c.ModelFallbacks {
+		models := parseChain(chain)
+		switch {
+		case len(models) < 2 || lo.Contains(models, ""):
+			errs = append(errs, fmt.Errorf("model fallback %q needs at least two models", chain))
+		case heads[models[0]]:
+			errs = append(errs, fmt.Errorf("model fallback %q: %s already has a chain", chain, models[0]))
+		case len(lo.Uniq(models)) != len(models):
+			errs = append(errs, fmt.Errorf("model fallback %q repeats a model", chain))
+		}
+		if len(models) > 0 {
+			heads[models[0]] = true
+		}
+	}
+	for name, o := range c.Keys {
+		if err := o.Validate(); err != nil {
+			errs = append(errs, fmt.Errorf("keys.%s: %w", name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// FallbackChain returns the models to try after model, in order.
+func (c RayConfig) FallbackChain(model string) []string {
+	for _, chain := range c.ModelFallbacks {
+		if models := parseChain(chain); models[0] == model {
+			return models[1:]
+		}
+	}
+	return nil
+}
+
+func parseChain(chain string) []string {
+	models := strings.Split(chain, "->")
+	for i := range models {
+		models[i] = strings.TrimSpace(models[i])
+	}
+	return models
 }
@@ -0,0 +1,216 @@
+package transcript
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Ken288yzs1/raychat/settings"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	currentName = "transcripts.jsonl"
+	// rotated files are gzipped, those the gzip failed for stay plain
+	rotatedGlob = "transcripts-*.jsonl*"
+)
+
+func Logger() *logrus.Entry {
+	return logrus.WithField("prefix", "transcript")
+}
+
+// Record is one exchange, the request as the client sent it, what raycast
+// was asked and what it answered.
+type Record struct {
+	Time           time.Time `json:"time"`
+	RequestID      string    `json:"request_id"`
+	CompletionID   string    `json:"completion_id"`
+	KeyID          string    `json:"key_id"`
+	Model          string    `json:"model"`
+	Outcome        string    `json:"outcome"`
+	Request        any       `json:"request"`
+	RayChatRequest any       `json:"raychat_request"`
+	Content        string    `json:"content"`
+	Reasoning      string    `json:"reasoning,omitempty"`
+}
+
+// Writer appends records to dir/transcripts.jsonl and rotates the file once it
+// grows past maxSize bytes or gets older than maxAge. Rotated files are
+// gzipped and deleted after retention.
+type Writer struct {
+	dir       string
+	maxSize   int64
+	maxAge    time.Duration
+	retention time.Duration
+
+	mu     sync.Mutex
+	file   *os.File
+	size   int64
+	opened time.Time
+}
+
+// New opens the writer configured by the TRANSCRIPT_* settings, it is nil
+// when transcripts are off without TRANSCRIPT_DIR.
+func New(conf settings.RayConfig) (*Writer, error) {
+	if conf.TranscriptDir == "" {
+		return nil, nil
+	}
+	w, err := Open(conf.TranscriptDir, int64(conf.TranscriptMaxSizeMB)<<20, conf.TranscriptMaxAge, conf.TranscriptRetention)
+	if err != nil {
+		return nil, fmt.Errorf("open transcript dir: %w", err)
+	}
+	Logger().Infof("writing transcripts to %s", conf.TranscriptDir)
+	return w, nil
+}
+
+func Open(dir string, maxSize int64, maxAge, retention time.Duration) (*Writer, error) {
+	w := &Writer{dir: dir, maxSize: maxSize, maxAge: maxAge, retention: retention}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+	if err := w.open(); err != nil {
+		return nil, err
+	}
+	go w.cleanup()
+	return w, nil
+}
+
+func (w *Writer) Write(r Record) {
+	raw, err := json.Marshal(r)
+	if err != nil {
+		Logger().WithError(err).Error("marshal transcript failed")
+		return
+	}
+	raw = append(raw, '\n')
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.due(int64(len(raw))) {
+		if err := w.rotate(); err != nil {
+			Logger().WithError(err).Error("rotate transcript failed")
+		}
+	}
+	n, err := w.file.Write(raw)
+	w.size += int64(n)
+	if err != nil {
+		Logger().WithError(err).Error("write transcript failed")
+	}
+}
+
+func (w *Writer) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.file.Close()
+}
+
+func (w *Writer) due(next int64) bool {
+	if w.size == 0 {
+		return false
+	}
+	if w.maxSize > 0 && w.size+next > w.maxSize {
+		return true
+	}
+	return w.maxAge > 0 && time.Since(w.opened) > w.maxAge
+}
+
+func (w *Writer) open() error {
+	path := filepath.Join(w.dir, currentName)
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	w.file, w.size, w.opened = f, info.Size(), info.ModTime()
+	if w.size == 0 {
+		w.opened = time.Now()
+	}
+	return nil
+}
+
+// rotate must be called with the lock held. The current file is renamed
+// while still open, so that on any failure writes carry on with the old
+// handle instead of a closed one.
+func (w *Writer) rotate() error {
+	old := w.file
+	current := filepath.Join(w.dir, currentName)
+	rotated := filepath.Join(w.dir, "transcripts-"+time.Now().UTC().Format("20060102T150405.000")+".jsonl")
+	if err := os.Rename(current, rotated); err != nil {
+		return err
+	}
+	if err := w.open(); err != nil {
+		// move the file back, the old handle keeps writing to the current
+		// path and the next rotation starts over
+		if rerr := os.Rename(rotated, current); rerr != nil {
+			Logger().WithError(rerr).Error("restore transcript after failed rotation failed")
+		}
+		return err
+	}
+	if err := old.Close(); err != nil {
+		Logger().WithError(err).Error("close rotated transcript failed")
+	}
+	go func() {
+		if err := compress(rotated); err != nil {
+			Logger().WithError(err).Error("gzip transcript failed")
+		}
+		w.cleanup()
+	}()
+	return nil
+}
+
+func compress(path string) error {
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	dst, err := os.OpenFile(path+".gz", os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	zw := gzip.NewWriter(dst)
+	if _, err := io.Copy(zw, src); err != nil {
+		dst.Close()
+		return err
+	}
+	if err := zw.Close(); err != nil {
+		dst.Close()
+		return err
+	}
+	if err := dst.Close(); err != nil {
+		return err
+	}
+	return os.Remove(path)
+}
+
+// cleanup drops rotated files past the retention window.
+func (w *Writer) cleanup() {
+	if w.retention <= 0 {
+		return
+	}
+	matches, err := filepath.Glob(filepath.Join(w.dir, rotatedGlob))
+	if err != nil {
+		return
+	}
+	for _, m := range matches {
+		info, err := os.Stat(m)
+		if err != nil || time.Since(info.ModTime()) < w.retention {
+			continue
+		}
+		if err := os.Remove(m); err != nil {
+			Logger().WithError(err).Warnf("remove old transcript %s failed", strings.TrimPrefix(m, w.dir))
+			continue
+		}
+		Logger().Infof("removed expired transcript %s", filepath.Base(m))
+	}
+}
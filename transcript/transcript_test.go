@@ -0,0 +1,78 @@
+package transcript
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestCleanup(t *testing.T) {
+	dir := t.TempDir()
+	old := time.Now().Add(-48 * time.Hour)
+	files := map[string]time.Time{
+		currentName: old,
+		"transcripts-20240101T000000.000.jsonl.gz": old,
+		"transcripts-20240101T010000.000.jsonl":    old, // its gzip failed
+		"transcripts-20240102T000000.000.jsonl.gz": time.Now(),
+		"notes.txt": old,
+	}
+	for name, mtime := range files {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte("{}\n"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, mtime, mtime); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	w := &Writer{dir: dir, retention: 24 * time.Hour}
+	w.cleanup()
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var left []string
+	for _, e := range entries {
+		left = append(left, e.Name())
+	}
+	want := []string{"notes.txt", "transcripts-20240102T000000.000.jsonl.gz", currentName}
+	if !slices.Equal(left, want) {
+		t.Errorf("left %v, want %v", left, want)
+	}
+}
+
+func TestRotate(t *testing.T) {
+	dir := t.TempDir()
+	w, err := Open(dir, 64, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	w.Write(Record{Content: "first"})
+	w.Write(Record{Content: "second"})
+
+	// the gzip runs in the background
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		rotated, _ := filepath.Glob(filepath.Join(dir, "transcripts-*.jsonl.gz"))
+		if len(rotated) == 1 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("rotated files %v", rotated)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	raw, err := os.ReadFile(filepath.Join(dir, currentName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Count(raw, []byte("\n")) != 1 {
+		t.Errorf("current file has %q", raw)
+	}
+}
@@ -0,0 +1,96 @@
+package transport
+
+import (
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/Ken288yzs1/raychat/settings"
+
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	client     *http.Client
+	clientOnce sync.Once
+)
+
+func Logger() *logrus.Entry {
+	return logrus.WithField("prefix", "transport")
+}
+
+// Client returns the http client shared by every upstream call, so that
+// connections to raycast are pooled instead of dialed per request.
+func Client() *http.Client {
+	clientOnce.Do(func() {
+		client = NewClient(Proxy)
+	})
+	return client
+}
+
+// NewClient builds a client with the UPSTREAM_* timeouts and pool size,
+// going through proxy.
+func NewClient(proxy func(*http.Request) (*url.URL, error)) *http.Client {
+	conf := settings.Get()
+	dialer := &net.Dialer{
+		Timeout:   conf.UpstreamConnectTimeout,
+		KeepAlive: 30 * time.Second,
+	}
+	var rt http.RoundTripper = &http.Transport{
+		Proxy:                 proxy,
+		DialContext:           dialer.DialContext,
+		ForceAttemptHTTP2:     true,
+		MaxIdleConns:          conf.UpstreamMaxIdleConns,
+		MaxIdleConnsPerHost:   conf.UpstreamMaxIdleConns,
+		IdleConnTimeout:       conf.UpstreamIdleTimeout,
+		TLSHandshakeTimeout:   conf.UpstreamConnectTimeout,
+		ResponseHeaderTimeout: conf.UpstreamFirstByteTimeout,
+		ExpectContinueTimeout: time.Second,
+	}
+	if conf.Debug {
+		rt = &debugTransport{next: rt}
+	}
+	// no overall timeout here, streamed completions can legitimately run for minutes
+	return &http.Client{Transport: rt}
+}
+
+// Proxy picks the outbound proxy, UPSTREAM_PROXY wins over the usual
+// HTTP_PROXY/HTTPS_PROXY/NO_PROXY variables. socks5:// urls are accepted.
+func Proxy(req *http.Request) (*url.URL, error) {
+	if raw := settings.Get().UpstreamProxy; raw != "" {
+		return url.Parse(raw)
+	}
+	return http.ProxyFromEnvironment(req)
+}
+
+// debugTransport dumps upstream traffic when DEBUG is on, with the bearer
+// token masked. Response bodies are left alone since most of them are streams.
+type debugTransport struct {
+	next http.RoundTripper
+}
+
+func (t *debugTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	redacted := req.Clone(req.Context())
+	if redacted.Header.Get("Authorization") != "" {
+		redacted.Header.Set("Authorization", "Bearer [REDACTED]")
+	}
+	if req.GetBody != nil {
+		redacted.Body, _ = req.GetBody()
+	}
+	if dump, err := httputil.DumpRequestOut(redacted, req.GetBody != nil); err == nil {
+		Logger().Debugf("upstream request:\n%s", dump)
+	}
+
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		Logger().WithError(err).Debug("upstream request failed")
+		return resp, err
+	}
+	if dump, err := httputil.DumpResponse(resp, false); err == nil {
+		Logger().Debugf("upstream response:\n%s", dump)
+	}
+	return resp, nil
+}
@@ -0,0 +1,31 @@
+package usage
+
+import (
+	"net/http"
+
+	"github.com/Ken288yzs1/raychat/keys"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AnonymousKey is what completions are recorded under when auth is off.
+const AnonymousKey = "anonymous"
+
+// KeyID names the caller in the ledger.
+func KeyID(c *gin.Context) string {
+	if key, ok := keys.FromContext(c); ok {
+		return key.ID
+	}
+	return AnonymousKey
+}
+
+// UsageEndpoint reports the calling key's own usage.
+func (l *Ledger) UsageEndpoint(c *gin.Context) {
+	f, err := FilterFromQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	f.KeyID = KeyID(c)
+	l.WriteReport(c, f)
+}
@@ -0,0 +1,284 @@
+package usage
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	OutcomeOK        = "ok"
+	OutcomeError     = "error"
+	OutcomeCancelled = "cancelled"
+	OutcomeTimeout   = "timeout"
+)
+
+func Logger() *logrus.Entry {
+	return logrus.WithField("prefix", "usage")
+}
+
+// Record is one completion as written to the ledger.
+type Record struct {
+	Time      time.Time `json:"time"`
+	KeyID     string    `json:"key_id"`
+	Model     string    `json:"model"`
+	Provider  string    `json:"provider"`
+	Stream    bool      `json:"stream"`
+	Tokens    Tokens    `json:"tokens"`
+	LatencyMs int64     `json:"latency_ms"`
+	Outcome   string    `json:"outcome"`
+}
+
+// total sums the records of one key, day and model.
+type total struct {
+	provider  string
+	requests  int
+	errors    int
+	tokens    Tokens
+	latencyMs int64
+}
+
+// days holds one key's totals by day (UTC midnight) and model.
+type days map[time.Time]map[string]*total
+
+// Ledger appends records to a jsonl file and keeps daily totals per key and
+// model in memory for reporting and quotas. A ledger without a path only
+// keeps memory. Days older than the retention are dropped from memory and,
+// on the next start, from the file.
+type Ledger struct {
+	mu        sync.RWMutex
+	file      *os.File
+	retention int
+	totals    map[string]days
+	pruned    time.Time
+}
+
+func newLedger(retention int) *Ledger {
+	return &Ledger{retention: retention, totals: map[string]days{}}
+}
+
+// Open loads the ledger at path, keeping retention days of history, zero
+// keeps everything. Without a path the ledger only keeps memory.
+func Open(path string, retention int) (*Ledger, error) {
+	l := newLedger(retention)
+	if path == "" {
+		Logger().Info("usage is kept in memory only, set USAGE_FILE to keep it across restarts")
+		return l, nil
+	}
+	if err := l.load(path); err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	l.file = f
+	return l, nil
+}
+
+// load sums up the file, then rewrites it without the records past the
+// retention if there are any.
+func (l *Ledger) load(path string) error {
+	cutoff := l.cutoff(time.Now())
+	stale := 0
+	err := scanRecords(path, func(r Record, _ []byte) {
+		if r.Time.Before(cutoff) {
+			stale++
+			return
+		}
+		l.add(r)
+	})
+	if err != nil || stale == 0 {
+		return err
+	}
+	Logger().Infof("drop %d usage records older than %d days from %s", stale, l.retention, path)
+	return compact(path, cutoff)
+}
+
+func scanRecords(path string, fn func(r Record, line []byte)) error {
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var r Record
+		if err := json.Unmarshal(scanner.Bytes(), &r); err != nil {
+			Logger().WithError(err).Warn("skip broken usage record")
+			continue
+		}
+		fn(r, scanner.Bytes())
+	}
+	return scanner.Err()
+}
+
+// compact replaces the file with its records from cutoff on.
+func compact(path string, cutoff time.Time) error {
+	tmp, err := os.OpenFile(path+".tmp", os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(tmp)
+	err = scanRecords(path, func(r Record, line []byte) {
+		if !r.Time.Before(cutoff) {
+			w.Write(line)
+			w.WriteByte('\n')
+		}
+	})
+	if err == nil {
+		err = w.Flush()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("compact usage file: %w", err)
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// cutoff is the first day still kept, zero when everything is.
+func (l *Ledger) cutoff(now time.Time) time.Time {
+	if l.retention <= 0 {
+		return time.Time{}
+	}
+	return day(now).AddDate(0, 0, -l.retention+1)
+}
+
+func day(t time.Time) time.Time {
+	return t.UTC().Truncate(24 * time.Hour)
+}
+
+func (l *Ledger) add(r Record) {
+	byDay, ok := l.totals[r.KeyID]
+	if !ok {
+		byDay = days{}
+		l.totals[r.KeyID] = byDay
+	}
+	d := day(r.Time)
+	models, ok := byDay[d]
+	if !ok {
+		models = map[string]*total{}
+		byDay[d] = models
+	}
+	t, ok := models[r.Model]
+	if !ok {
+		t = &total{provider: r.Provider}
+		models[r.Model] = t
+	}
+	t.requests++
+	if r.Outcome != OutcomeOK {
+		t.errors++
+	}
+	t.tokens.Prompt += r.Tokens.Prompt
+	t.tokens.Completion += r.Tokens.Completion
+	t.tokens.Reasoning += r.Tokens.Reasoning
+	t.latencyMs += r.LatencyMs
+}
+
+// prune drops the days past the retention, once a day.
+func (l *Ledger) prune(now time.Time) {
+	cutoff := l.cutoff(now)
+	if cutoff.IsZero() || !cutoff.After(l.pruned) {
+		return
+	}
+	l.pruned = cutoff
+	for key, byDay := range l.totals {
+		for d := range byDay {
+			if d.Before(cutoff) {
+				delete(byDay, d)
+			}
+		}
+		if len(byDay) == 0 {
+			delete(l.totals, key)
+		}
+	}
+}
+
+func (l *Ledger) Add(r Record) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.prune(time.Now())
+	l.add(r)
+	if l.file == nil {
+		return
+	}
+	raw, err := json.Marshal(r)
+	if err != nil {
+		Logger().WithError(err).Error("marshal usage record failed")
+		return
+	}
+	if _, err := l.file.Write(append(raw, '\n')); err != nil {
+		Logger().WithError(err).Error("write usage record failed")
+	}
+}
+
+// Aggregates returns the daily totals matching f, ordered by day, key and
+// model.
+func (l *Ledger) Aggregates(f Filter) []Aggregate {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	out := []Aggregate{}
+	for key, byDay := range l.totals {
+		if f.KeyID != "" && key != f.KeyID {
+			continue
+		}
+		for d, models := range byDay {
+			if !f.matchDay(d) {
+				continue
+			}
+			for model, t := range models {
+				if f.Model != "" && model != f.Model {
+					continue
+				}
+				out = append(out, t.aggregate(d, key, model))
+			}
+		}
+	}
+	sortAggregates(out)
+	return out
+}
+
+// Usage sums what key spent since the start of a day on the models match
+// accepts.
+func (l *Ledger) Usage(key string, since time.Time, match func(model string) bool) (requests, tokens int) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	since = day(since)
+	for d, models := range l.totals[key] {
+		if d.Before(since) {
+			continue
+		}
+		for model, t := range models {
+			if match(model) {
+				requests += t.requests
+				tokens += t.tokens.Total()
+			}
+		}
+	}
+	return requests, tokens
+}
+
+func (l *Ledger) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.file == nil {
+		return nil
+	}
+	return l.file.Close()
+}
@@ -0,0 +1,142 @@
+package usage
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func writeRecords(t *testing.T, path string, lines ...any) {
+	t.Helper()
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	for _, line := range lines {
+		raw, ok := line.(string)
+		if !ok {
+			b, err := json.Marshal(line)
+			if err != nil {
+				t.Fatal(err)
+			}
+			raw = string(b)
+		}
+		f.WriteString(raw + "\n")
+	}
+}
+
+func readRecords(t *testing.T, path string) []Record {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	var out []Record
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var r Record
+		if err := json.Unmarshal(scanner.Bytes(), &r); err != nil {
+			t.Fatalf("bad line %q: %v", scanner.Text(), err)
+		}
+		out = append(out, r)
+	}
+	return out
+}
+
+func TestOpenRetention(t *testing.T) {
+	now := time.Now().UTC()
+	records := []any{
+		Record{Time: now.AddDate(0, 0, -10), KeyID: "key_1", Model: "gpt-4o", Tokens: Tokens{Prompt: 100}, Outcome: OutcomeOK},
+		Record{Time: now.AddDate(0, 0, -3), KeyID: "key_1", Model: "gpt-4o", Tokens: Tokens{Prompt: 10}, Outcome: OutcomeOK},
+		"not json",
+		Record{Time: now, KeyID: "key_1", Model: "gpt-4o", Tokens: Tokens{Prompt: 1}, Outcome: OutcomeOK},
+	}
+	tests := []struct {
+		name       string
+		retention  int
+		wantTokens int
+		// wantLines is what a rewritten file holds after Open
+		wantLines int
+		rewritten bool
+	}{
+		{name: "keep everything", retention: 0, wantTokens: 111},
+		{name: "trim past retention", retention: 7, wantTokens: 11, wantLines: 2, rewritten: true},
+		{name: "only today", retention: 1, wantTokens: 1, wantLines: 1, rewritten: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "usage.jsonl")
+			writeRecords(t, path, records...)
+			before, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			l, err := Open(path, tt.retention)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer l.Close()
+
+			_, tokens := l.Usage("key_1", time.Time{}, func(string) bool { return true })
+			if tokens != tt.wantTokens {
+				t.Errorf("tokens = %d, want %d", tokens, tt.wantTokens)
+			}
+			after, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if rewritten := string(after) != string(before); rewritten != tt.rewritten {
+				t.Errorf("rewritten = %v, want %v", rewritten, tt.rewritten)
+			}
+			if !tt.rewritten {
+				return
+			}
+			// compaction keeps the records as they were written
+			got := readRecords(t, path)
+			if len(got) != tt.wantLines {
+				t.Fatalf("file has %d records, want %d", len(got), tt.wantLines)
+			}
+			if last := got[len(got)-1]; !last.Time.Equal(now) || last.Tokens.Prompt != 1 {
+				t.Errorf("last record = %+v", last)
+			}
+			if _, err := os.Stat(path + ".tmp"); !os.IsNotExist(err) {
+				t.Errorf("temp file left behind: %v", err)
+			}
+		})
+	}
+}
+
+func TestAddReopen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "data", "usage.jsonl")
+	l, err := Open(path, 30)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	l.Add(Record{Time: now, KeyID: "key_1", Model: "gpt-4o", Tokens: Tokens{Prompt: 3, Completion: 4}, Outcome: OutcomeOK})
+	l.Add(Record{Time: now, KeyID: "key_1", Model: "o3", Tokens: Tokens{Prompt: 5}, Outcome: OutcomeError})
+	l.Add(Record{Time: now, KeyID: "key_2", Model: "gpt-4o", Tokens: Tokens{Prompt: 1}, Outcome: OutcomeOK})
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	l, err = Open(path, 30)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	requests, tokens := l.Usage("key_1", now, func(m string) bool { return m == "gpt-4o" })
+	if requests != 1 || tokens != 7 {
+		t.Errorf("key_1 gpt-4o = %d requests, %d tokens, want 1, 7", requests, tokens)
+	}
+	aggs := l.Aggregates(Filter{KeyID: "key_1"})
+	if len(aggs) != 2 {
+		t.Fatalf("got %d aggregates, want 2: %+v", len(aggs), aggs)
+	}
+}
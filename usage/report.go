@@ -0,0 +1,120 @@
+package usage
+
+import (
+	"encoding/csv"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const dateLayout = "2006-01-02"
+
+// Filter selects usage, zero fields match everything. To is exclusive.
+type Filter struct {
+	KeyID string
+	Model string
+	From  time.Time
+	To    time.Time
+}
+
+// matchDay is whether the day starting at d is within the filter, filters
+// are whole days.
+func (f Filter) matchDay(d time.Time) bool {
+	if !f.From.IsZero() && d.Before(day(f.From)) {
+		return false
+	}
+	if !f.To.IsZero() && !d.Before(f.To) {
+		return false
+	}
+	return true
+}
+
+// Aggregate sums the records of one day, model and key.
+type Aggregate struct {
+	Date             string `json:"date"`
+	KeyID            string `json:"key_id"`
+	Model            string `json:"model"`
+	Provider         string `json:"provider"`
+	Requests         int    `json:"requests"`
+	Errors           int    `json:"errors"`
+	PromptTokens     int    `json:"prompt_tokens"`
+	CompletionTokens int    `json:"completion_tokens"`
+	ReasoningTokens  int    `json:"reasoning_tokens"`
+	TotalTokens      int    `json:"total_tokens"`
+	AvgLatencyMs     int64  `json:"avg_latency_ms"`
+}
+
+func (t *total) aggregate(d time.Time, key, model string) Aggregate {
+	return Aggregate{
+		Date:             d.Format(dateLayout),
+		KeyID:            key,
+		Model:            model,
+		Provider:         t.provider,
+		Requests:         t.requests,
+		Errors:           t.errors,
+		PromptTokens:     t.tokens.Prompt,
+		CompletionTokens: t.tokens.Completion,
+		ReasoningTokens:  t.tokens.Reasoning,
+		TotalTokens:      t.tokens.Total(),
+		AvgLatencyMs:     t.latencyMs / int64(t.requests),
+	}
+}
+
+func sortAggregates(out []Aggregate) {
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Date != out[j].Date {
+			return out[i].Date < out[j].Date
+		}
+		if out[i].KeyID != out[j].KeyID {
+			return out[i].KeyID < out[j].KeyID
+		}
+		return out[i].Model < out[j].Model
+	})
+}
+
+// FilterFromQuery reads from/to (YYYY-MM-DD, to inclusive) and model from the query.
+func FilterFromQuery(c *gin.Context) (Filter, error) {
+	f := Filter{Model: c.Query("model")}
+	if from := c.Query("from"); from != "" {
+		t, err := time.Parse(dateLayout, from)
+		if err != nil {
+			return f, err
+		}
+		f.From = t
+	}
+	if to := c.Query("to"); to != "" {
+		t, err := time.Parse(dateLayout, to)
+		if err != nil {
+			return f, err
+		}
+		f.To = t.AddDate(0, 0, 1)
+	}
+	return f, nil
+}
+
+// WriteReport answers with the aggregates as json, or as csv with format=csv.
+func (l *Ledger) WriteReport(c *gin.Context, f Filter) {
+	aggs := l.Aggregates(f)
+	if c.Query("format") != "csv" {
+		c.JSON(http.StatusOK, gin.H{"object": "list", "data": aggs})
+		return
+	}
+
+	c.Header("Content-Type", "text/csv")
+	c.Header("Content-Disposition", `attachment; filename="usage.csv"`)
+	c.Status(http.StatusOK)
+	w := csv.NewWriter(c.Writer)
+	w.Write([]string{"date", "key_id", "model", "provider", "requests", "errors", "prompt_tokens", "completion_tokens", "reasoning_tokens", "total_tokens", "avg_latency_ms"})
+	for _, a := range aggs {
+		w.Write([]string{
+			a.Date, a.KeyID, a.Model, a.Provider,
+			strconv.Itoa(a.Requests), strconv.Itoa(a.Errors),
+			strconv.Itoa(a.PromptTokens), strconv.Itoa(a.CompletionTokens), strconv.Itoa(a.ReasoningTokens), strconv.Itoa(a.TotalTokens),
+			strconv.FormatInt(a.AvgLatencyMs, 10),
+		})
+	}
+	w.Flush()
+}
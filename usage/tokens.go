@@ -0,0 +1,40 @@
+package usage
+
+import (
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+)
+
+const contextKey = "raychat/usage"
+
+// Tokens counts what one completion consumed. Raycast doesn't report usage,
+// so these are estimates.
+type Tokens struct {
+	Prompt     int `json:"prompt_tokens"`
+	Completion int `json:"completion_tokens"`
+	Reasoning  int `json:"reasoning_tokens"`
+}
+
+func (t Tokens) Total() int {
+	return t.Prompt + t.Completion + t.Reasoning
+}
+
+// Estimate uses the usual rule of thumb of four characters per token.
+func Estimate(text string) int {
+	return (utf8.RuneCountInString(text) + 3) / 4
+}
+
+// Set records the completion's usage for middlewares running after the handler.
+func Set(c *gin.Context, t Tokens) {
+	c.Set(contextKey, t)
+}
+
+func FromContext(c *gin.Context) (Tokens, bool) {
+	v, ok := c.Get(contextKey)
+	if !ok {
+		return Tokens{}, false
+	}
+	t, ok := v.(Tokens)
+	return t, ok
+}